@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetTable locates a table (list object) in the package
+type sheetTable struct {
+	Sheet      string
+	Part       string
+	Name       string
+	Ref        string
+	HeaderRows int
+	TotalsRows int
+}
+
+// findTable looks up a table by name, case-insensitively as Excel does, on one
+// worksheet or, if sheet is empty, on every worksheet
+func findTable(f *excelize.File, sheet, name string) (*sheetTable, error) {
+	sheets := []string{sheet}
+	if sheet == "" {
+		sheets = f.GetSheetList()
+	}
+	parts := sheetParts(f)
+	for _, s := range sheets {
+		part, ok := parts[s]
+		if !ok || part.Path == "" {
+			return nil, fmt.Errorf("worksheet '%s' not found", s)
+		}
+		for _, rel := range readRelationships(f, part.Path).Relationships {
+			if path.Base(rel.Type) != "table" {
+				continue
+			}
+			tablePart := resolvePartTarget(part.Path, rel.Target)
+			var parsed struct {
+				Name           string `xml:"name,attr"`
+				DisplayName    string `xml:"displayName,attr"`
+				Ref            string `xml:"ref,attr"`
+				HeaderRowCount *int   `xml:"headerRowCount,attr"`
+				TotalsRowCount int    `xml:"totalsRowCount,attr"`
+			}
+			if err := unmarshalPart(f, tablePart, &parsed); err != nil {
+				return nil, err
+			}
+			if !strings.EqualFold(parsed.Name, name) && !strings.EqualFold(parsed.DisplayName, name) {
+				continue
+			}
+			table := &sheetTable{
+				Sheet:      s,
+				Part:       tablePart,
+				Name:       parsed.DisplayName,
+				Ref:        parsed.Ref,
+				HeaderRows: 1,
+				TotalsRows: parsed.TotalsRowCount,
+			}
+			if parsed.HeaderRowCount != nil {
+				table.HeaderRows = *parsed.HeaderRowCount
+			}
+			if table.Name == "" {
+				table.Name = parsed.Name
+			}
+			return table, nil
+		}
+	}
+	if sheet != "" {
+		return nil, fmt.Errorf("table '%s' not found on sheet '%s'", name, sheet)
+	}
+	return nil, fmt.Errorf("table '%s' not found", name)
+}
+
+// tableRefAttr matches the ref attribute of a start tag
+var tableRefAttr = regexp.MustCompile(`\sref\s*=\s*("[^"]*"|'[^']*')`)
+
+// resizeTable sets the range of a table and of its autofilter, which leaves out
+// any totals row. Only the ref attributes of the table element and of its own
+// autoFilter element change.
+func resizeTable(f *excelize.File, table *sheetTable, ref string) error {
+	data, ok := f.Pkg.Load(table.Part)
+	if !ok {
+		return fmt.Errorf("table part %s not found", table.Part)
+	}
+	content, _ := data.([]byte)
+	col1, row1, col2, row2, err := parseRangeRef(ref)
+	if err != nil {
+		return err
+	}
+	filterStart, _ := excelize.CoordinatesToCellName(col1, row1)
+	filterEnd, _ := excelize.CoordinatesToCellName(col2, max(row1, row2-table.TotalsRows))
+	refs := []string{ref, filterStart + ":" + filterEnd}
+
+	var edited bytes.Buffer
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	copied, depth, tableFound := 0, 0, false
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse table part %s: %w", table.Part, err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if (depth == 0 && element.Name.Local == "table") || (depth == 1 && element.Name.Local == "autoFilter") {
+				end := int(decoder.InputOffset())
+				loc := tableRefAttr.FindIndex(content[offset:end])
+				if loc == nil {
+					return fmt.Errorf("table part %s has no ref on its %s element", table.Part, element.Name.Local)
+				}
+				edited.Write(content[copied : offset+loc[0]])
+				fmt.Fprintf(&edited, ` ref="%s"`, refs[depth])
+				copied = offset + loc[1]
+				tableFound = tableFound || depth == 0
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if !tableFound {
+		return fmt.Errorf("table part %s has no table element", table.Part)
+	}
+	edited.Write(content[copied:])
+	f.Pkg.Store(table.Part, edited.Bytes())
+	table.Ref = ref
+	return nil
+}
+
+// parseColumnRange parses a column range such as "A:D", or a single column such
+// as "C", into 1-based column numbers
+func parseColumnRange(columns string) (col1, col2 int, err error) {
+	first, last, found := strings.Cut(strings.ReplaceAll(columns, "$", ""), ":")
+	if !found {
+		last = first
+	}
+	if col1, err = excelize.ColumnNameToNumber(first); err != nil {
+		return 0, 0, fmt.Errorf("invalid column range %q, expected format like 'A:D'", columns)
+	}
+	if col2, err = excelize.ColumnNameToNumber(last); err != nil {
+		return 0, 0, fmt.Errorf("invalid column range %q, expected format like 'A:D'", columns)
+	}
+	if col1 > col2 {
+		col1, col2 = col2, col1
+	}
+	return col1, col2, nil
+}
+
+// lastUsedRow returns the last row with a value in columns col1 to col2, or 0 if
+// they are empty. A positive maxRow limits the search to the rows up to it.
+func lastUsedRow(f *excelize.File, sheet string, col1, col2, maxRow int) (int, error) {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	last := 0
+	for rowNum := 1; rows.Next() && (maxRow <= 0 || rowNum <= maxRow); rowNum++ {
+		cols, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return 0, err
+		}
+		for col := col1; col <= col2 && col <= len(cols); col++ {
+			if cols[col-1] != "" {
+				last = rowNum
+				break
+			}
+		}
+	}
+	return last, rows.Error()
+}
+
+// tableAppendRow returns the first row to append count rows of width values to a
+// table: the row below its last row with data. Empty rows at the end of the table
+// are filled first, and the rows the table grows into must be empty.
+func tableAppendRow(f *excelize.File, table *sheetTable, width, count int) (int, error) {
+	if table.TotalsRows > 0 {
+		return 0, fmt.Errorf("table '%s' has a totals row; remove it before appending", table.Name)
+	}
+	col1, row1, col2, row2, err := parseRangeRef(table.Ref)
+	if err != nil {
+		return 0, err
+	}
+	if width > col2-col1+1 {
+		return 0, fmt.Errorf("rows have %d values but table '%s' has %d columns", width, table.Name, col2-col1+1)
+	}
+	last, err := lastUsedRow(f, table.Sheet, col1, col2, 0)
+	if err != nil {
+		return 0, err
+	}
+	if last > row2 {
+		// Cells below the table hold data, so only its empty rows can be used
+		if last, err = lastUsedRow(f, table.Sheet, col1, col2, row2); err != nil {
+			return 0, err
+		}
+		if max(last+1, row1+table.HeaderRows)+count-1 > row2 {
+			return 0, fmt.Errorf("the cells below table '%s' are not empty", table.Name)
+		}
+	}
+	return max(last+1, row1+table.HeaderRows), nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// richTextRun is a run of text with its font, as accepted and returned by the comment tools
+type richTextRun struct {
+	Text       string  `json:"text"`
+	Bold       bool    `json:"bold,omitempty"`
+	Italic     bool    `json:"italic,omitempty"`
+	Underline  string  `json:"underline,omitempty"`
+	FontSize   float64 `json:"font_size,omitempty"`
+	FontFamily string  `json:"font_family,omitempty"`
+	FontColor  string  `json:"font_color,omitempty"`
+}
+
+// toExcelize converts the run to an excelize rich text run
+func (r richTextRun) toExcelize() excelize.RichTextRun {
+	run := excelize.RichTextRun{Text: r.Text}
+	if r.Bold || r.Italic || r.Underline != "" || r.FontSize > 0 || r.FontFamily != "" || r.FontColor != "" {
+		run.Font = &excelize.Font{
+			Bold:      r.Bold,
+			Italic:    r.Italic,
+			Underline: r.Underline,
+			Size:      r.FontSize,
+			Family:    r.FontFamily,
+			Color:     r.FontColor,
+		}
+	}
+	return run
+}
+
+// fromExcelizeRun converts an excelize rich text run
+func fromExcelizeRun(run excelize.RichTextRun) richTextRun {
+	r := richTextRun{Text: run.Text}
+	if run.Font != nil {
+		r.Bold = run.Font.Bold
+		r.Italic = run.Font.Italic
+		r.Underline = run.Font.Underline
+		r.FontSize = run.Font.Size
+		r.FontFamily = run.Font.Family
+		r.FontColor = run.Font.Color
+	}
+	return r
+}
+
+// commentInfo describes a cell comment
+type commentInfo struct {
+	Sheet    string        `json:"sheet"`
+	Cell     string        `json:"cell"`
+	Author   string        `json:"author"`
+	Text     string        `json:"text"`
+	RichText []richTextRun `json:"rich_text,omitempty"`
+}
+
+// newCommentInfo describes a comment. Rich text is only included when a run is formatted.
+func newCommentInfo(sheet string, c excelize.Comment) commentInfo {
+	info := commentInfo{Sheet: sheet, Cell: c.Cell, Author: c.Author, Text: commentText(c)}
+	for _, run := range c.Paragraph {
+		if run.Font != nil && (run.Font.Bold || run.Font.Italic || run.Font.Underline != "") {
+			info.RichText = make([]richTextRun, len(c.Paragraph))
+			for i, run := range c.Paragraph {
+				info.RichText[i] = fromExcelizeRun(run)
+			}
+			break
+		}
+	}
+	return info
+}
+
+// commentText returns the plain text of a comment
+func commentText(c excelize.Comment) string {
+	var text strings.Builder
+	text.WriteString(c.Text)
+	for _, run := range c.Paragraph {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+// normalizeCell returns a cell reference in the form excelize stores comments
+// under, so that "b2" and "$B$2" both become "B2"
+func normalizeCell(cell string) (string, error) {
+	col, row, err := excelize.CellNameToCoordinates(cell)
+	if err != nil {
+		return "", err
+	}
+	return excelize.CoordinatesToCellName(col, row)
+}
+
+// findComment returns the comment of a cell, if any
+func findComment(f *excelize.File, sheet, cell string) (*excelize.Comment, error) {
+	cell, err := normalizeCell(cell)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := f.GetComments(sheet)
+	if err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		if comments[i].Cell == cell {
+			return &comments[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// replyParagraph appends a reply to an existing comment the way Excel lays out
+// notes: the author's name in bold on a new line, followed by the text
+func replyParagraph(existing *excelize.Comment, author string, reply []excelize.RichTextRun) []excelize.RichTextRun {
+	var runs []excelize.RichTextRun
+	if existing.Text != "" {
+		runs = append(runs, excelize.RichTextRun{Text: existing.Text})
+	}
+	runs = append(runs, existing.Paragraph...)
+	runs = append(runs, excelize.RichTextRun{Text: "\n" + author + ":", Font: &excelize.Font{Bold: true, Size: 9}})
+	if len(reply) > 0 {
+		reply[0].Text = "\n" + reply[0].Text
+	}
+	return append(runs, reply...)
+}
+
+// setComment adds a comment to a cell, replacing any comment it already has
+func setComment(f *excelize.File, sheet string, comment excelize.Comment) error {
+	if existing, err := findComment(f, sheet, comment.Cell); err != nil {
+		return err
+	} else if existing != nil {
+		if err := f.DeleteComment(sheet, comment.Cell); err != nil {
+			return err
+		}
+	}
+	if comment.Author == "" {
+		comment.Author = "Author"
+	}
+	if len(comment.Author) > excelize.MaxFieldLength {
+		comment.Author = comment.Author[:excelize.MaxFieldLength]
+	}
+	counts := make(map[string]int, len(f.Comments))
+	for part, comments := range f.Comments {
+		if comments != nil {
+			counts[part] = len(comments.CommentList.Comment)
+		}
+	}
+	if err := f.AddComment(sheet, comment); err != nil {
+		return err
+	}
+
+	// excelize attributes a comment to the first author when its author is
+	// already listed, so point the new comment at the right author. excelize
+	// matches authors case-insensitively and does not add an author that differs
+	// only in case, so an exact match is preferred over a case-insensitive one.
+	for part, comments := range f.Comments {
+		if comments == nil || len(comments.CommentList.Comment) == counts[part] {
+			continue
+		}
+		authorID := -1
+		for i, author := range comments.Authors.Author {
+			if author == comment.Author {
+				authorID = i
+				break
+			}
+			if authorID == -1 && strings.EqualFold(author, comment.Author) {
+				authorID = i
+			}
+		}
+		if authorID != -1 {
+			comments.CommentList.Comment[len(comments.CommentList.Comment)-1].AuthorID = authorID
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to set the author of the comment at %s", comment.Cell)
+}
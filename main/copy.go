@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// worksheetLayout is the minimal shape of a worksheet part needed to copy its
+// column and row settings and to find every cell that holds a value or a style
+type worksheetLayout struct {
+	Cols []struct {
+		Min          int     `xml:"min,attr"`
+		Max          int     `xml:"max,attr"`
+		Width        float64 `xml:"width,attr"`
+		Style        int     `xml:"style,attr"`
+		Hidden       bool    `xml:"hidden,attr"`
+		OutlineLevel uint8   `xml:"outlineLevel,attr"`
+	} `xml:"cols>col"`
+	Rows []struct {
+		R            int     `xml:"r,attr"`
+		Height       float64 `xml:"ht,attr"`
+		Hidden       bool    `xml:"hidden,attr"`
+		Style        int     `xml:"s,attr"`
+		CustomFormat bool    `xml:"customFormat,attr"`
+		OutlineLevel uint8   `xml:"outlineLevel,attr"`
+		Cells        []struct {
+			R     string `xml:"r,attr"`
+			Style int    `xml:"s,attr"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readWorksheetPart returns the XML of a worksheet part, including changes made
+// since the workbook was opened. excelize keeps a worksheet it has loaded in
+// memory and writes it back to the package when its rows are read, so reading
+// the rows refreshes the part. Worksheets larger than the unzip XML size limit
+// live in temporary files rather than the package; reading their properties
+// loads them first.
+func readWorksheetPart(f *excelize.File, sheet string) ([]byte, error) {
+	part, ok := sheetParts(f)[sheet]
+	if !ok || part.Path == "" {
+		return nil, fmt.Errorf("worksheet '%s' not found", sheet)
+	}
+	if _, ok := f.Pkg.Load(part.Path); !ok {
+		if _, err := f.GetSheetProps(sheet); err != nil {
+			return nil, fmt.Errorf("failed to read worksheet '%s': %w", sheet, err)
+		}
+	}
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worksheet '%s': %w", sheet, err)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read worksheet '%s': %w", sheet, err)
+	}
+	data, ok := f.Pkg.Load(part.Path)
+	if !ok {
+		return nil, fmt.Errorf("failed to read worksheet '%s': part %s is not loaded", sheet, part.Path)
+	}
+	content, _ := data.([]byte)
+	return content, nil
+}
+
+// readWorksheetLayout parses the column, row and cell layout of a worksheet as stored in the file
+func readWorksheetLayout(f *excelize.File, sheet string) (*worksheetLayout, error) {
+	content, err := readWorksheetPart(f, sheet)
+	if err != nil {
+		return nil, err
+	}
+	layout := &worksheetLayout{}
+	if err := xml.Unmarshal(content, layout); err != nil {
+		return nil, fmt.Errorf("failed to parse worksheet '%s': %w", sheet, err)
+	}
+	return layout, nil
+}
+
+// styleCopier re-creates cell styles of one workbook in another, mapping style IDs
+type styleCopier struct {
+	src, dst *excelize.File
+	ids      map[int]int
+}
+
+func (c *styleCopier) styleID(id int) (int, error) {
+	if id == 0 {
+		return 0, nil
+	}
+	if mapped, ok := c.ids[id]; ok {
+		return mapped, nil
+	}
+	style, err := c.src.GetStyle(id)
+	if err != nil {
+		return 0, err
+	}
+	mapped, err := c.dst.NewStyle(style)
+	if err != nil {
+		return 0, err
+	}
+	c.ids[id] = mapped
+	return mapped, nil
+}
+
+// copyWorksheetAcross copies a worksheet into another workbook: cell values,
+// formulas, rich text, styles, merged cells, column widths, row heights,
+// data validations, sheet properties, view settings and frozen panes.
+// The target sheet is created if it does not exist.
+func copyWorksheetAcross(src *excelize.File, srcSheet string, dst *excelize.File, dstSheet string) error {
+	layout, err := readWorksheetLayout(src, srcSheet)
+	if err != nil {
+		return err
+	}
+	if index, _ := dst.GetSheetIndex(dstSheet); index == -1 {
+		if _, err := dst.NewSheet(dstSheet); err != nil {
+			return err
+		}
+	}
+	styles := &styleCopier{src: src, dst: dst, ids: make(map[int]int)}
+
+	// Column and row settings come first so that cell styles take precedence
+	for _, col := range layout.Cols {
+		startCol, _ := excelize.ColumnNumberToName(col.Min)
+		endCol, _ := excelize.ColumnNumberToName(col.Max)
+		if col.Width > 0 {
+			if err := dst.SetColWidth(dstSheet, startCol, endCol, col.Width); err != nil {
+				return fmt.Errorf("failed to set column width: %w", err)
+			}
+		}
+		if col.Style != 0 {
+			styleID, err := styles.styleID(col.Style)
+			if err != nil {
+				return fmt.Errorf("failed to copy column style: %w", err)
+			}
+			if err := dst.SetColStyle(dstSheet, startCol+":"+endCol, styleID); err != nil {
+				return fmt.Errorf("failed to set column style: %w", err)
+			}
+		}
+		if col.Hidden {
+			if err := dst.SetColVisible(dstSheet, startCol+":"+endCol, false); err != nil {
+				return fmt.Errorf("failed to hide columns: %w", err)
+			}
+		}
+		for c := col.Min; c <= col.Max && col.OutlineLevel > 0; c++ {
+			name, _ := excelize.ColumnNumberToName(c)
+			if err := dst.SetColOutlineLevel(dstSheet, name, col.OutlineLevel); err != nil {
+				return fmt.Errorf("failed to set column outline level: %w", err)
+			}
+		}
+	}
+
+	rowNum := 0
+	for _, row := range layout.Rows {
+		rowNum++
+		if row.R > 0 {
+			rowNum = row.R
+		}
+		if row.Height > 0 {
+			if err := dst.SetRowHeight(dstSheet, rowNum, row.Height); err != nil {
+				return fmt.Errorf("failed to set row height: %w", err)
+			}
+		}
+		if row.CustomFormat && row.Style != 0 {
+			styleID, err := styles.styleID(row.Style)
+			if err != nil {
+				return fmt.Errorf("failed to copy row style: %w", err)
+			}
+			if err := dst.SetRowStyle(dstSheet, rowNum, rowNum, styleID); err != nil {
+				return fmt.Errorf("failed to set row style: %w", err)
+			}
+		}
+		if row.Hidden {
+			if err := dst.SetRowVisible(dstSheet, rowNum, false); err != nil {
+				return fmt.Errorf("failed to hide row: %w", err)
+			}
+		}
+		if row.OutlineLevel > 0 {
+			if err := dst.SetRowOutlineLevel(dstSheet, rowNum, row.OutlineLevel); err != nil {
+				return fmt.Errorf("failed to set row outline level: %w", err)
+			}
+		}
+
+		colNum := 0
+		for _, c := range row.Cells {
+			colNum++
+			cell := c.R
+			if cell == "" {
+				cell, _ = excelize.CoordinatesToCellName(colNum, rowNum)
+			} else if colNum, _, err = excelize.CellNameToCoordinates(cell); err != nil {
+				return err
+			}
+			if err := copyCell(src, srcSheet, cell, dst, dstSheet, cell); err != nil {
+				return fmt.Errorf("failed to copy cell %s: %w", cell, err)
+			}
+			if c.Style != 0 {
+				styleID, err := styles.styleID(c.Style)
+				if err != nil {
+					return fmt.Errorf("failed to copy style of cell %s: %w", cell, err)
+				}
+				if err := dst.SetCellStyle(dstSheet, cell, cell, styleID); err != nil {
+					return fmt.Errorf("failed to set style of cell %s: %w", cell, err)
+				}
+			}
+		}
+	}
+
+	mergeCells, err := src.GetMergeCells(srcSheet)
+	if err != nil {
+		return fmt.Errorf("failed to read merged cells: %w", err)
+	}
+	for _, mc := range mergeCells {
+		if err := dst.MergeCell(dstSheet, mc.GetStartAxis(), mc.GetEndAxis()); err != nil {
+			return fmt.Errorf("failed to merge cells: %w", err)
+		}
+	}
+
+	validations, err := src.GetDataValidations(srcSheet)
+	if err != nil {
+		return fmt.Errorf("failed to read data validations: %w", err)
+	}
+	for _, dv := range validations {
+		// Formulas are read unescaped but written as inner XML
+		dv.Formula1 = formulaXMLEscaper.Replace(dv.Formula1)
+		dv.Formula2 = formulaXMLEscaper.Replace(dv.Formula2)
+		if err := dst.AddDataValidation(dstSheet, dv); err != nil {
+			return fmt.Errorf("failed to add data validation: %w", err)
+		}
+	}
+
+	props, err := src.GetSheetProps(srcSheet)
+	if err != nil {
+		return fmt.Errorf("failed to read sheet properties: %w", err)
+	}
+	props.CodeName = nil
+	if err := dst.SetSheetProps(dstSheet, &props); err != nil {
+		return fmt.Errorf("failed to set sheet properties: %w", err)
+	}
+	if view, err := src.GetSheetView(srcSheet, 0); err == nil {
+		if err := dst.SetSheetView(dstSheet, 0, &view); err != nil {
+			return fmt.Errorf("failed to set sheet view: %w", err)
+		}
+	}
+	panes, err := src.GetPanes(srcSheet)
+	if err != nil {
+		return fmt.Errorf("failed to read panes: %w", err)
+	}
+	if panes.Freeze || panes.Split {
+		if err := dst.SetPanes(dstSheet, &panes); err != nil {
+			return fmt.Errorf("failed to set panes: %w", err)
+		}
+	}
+	return nil
+}
+
+// copyCell copies the value or formula of the cell srcCell to dstCell, keeping
+// its type. Formula cells are copied without their cached result, which Excel
+// recalculates, and their relative references move with the cell.
+func copyCell(src *excelize.File, srcSheet, srcCell string, dst *excelize.File, dstSheet, dstCell string) error {
+	formula, err := src.GetCellFormula(srcSheet, srcCell)
+	if err != nil {
+		return err
+	}
+	if formula != "" {
+		if srcCell != dstCell {
+			srcCol, srcRow, _ := excelize.CellNameToCoordinates(srcCell)
+			dstCol, dstRow, _ := excelize.CellNameToCoordinates(dstCell)
+			formula = shiftFormula(formula, dstCol-srcCol, dstRow-srcRow)
+		}
+		return dst.SetCellFormula(dstSheet, dstCell, formula)
+	}
+	cellType, err := src.GetCellType(srcSheet, srcCell)
+	if err != nil {
+		return err
+	}
+	raw, err := src.GetCellValue(srcSheet, srcCell, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return err
+	}
+	cell := dstCell
+	switch cellType {
+	case excelize.CellTypeBool:
+		err = dst.SetCellBool(dstSheet, cell, raw == "1" || raw == "TRUE")
+	case excelize.CellTypeSharedString, excelize.CellTypeInlineString:
+		runs, _ := src.GetCellRichText(srcSheet, srcCell)
+		if len(runs) > 1 || (len(runs) == 1 && runs[0].Font != nil) {
+			err = dst.SetCellRichText(dstSheet, cell, runs)
+		} else {
+			err = dst.SetCellStr(dstSheet, cell, raw)
+		}
+	case excelize.CellTypeDate:
+		if t, ok := parseISODate(raw); ok {
+			err = dst.SetCellValue(dstSheet, cell, t)
+		} else {
+			err = dst.SetCellStr(dstSheet, cell, raw)
+		}
+	case excelize.CellTypeError:
+		err = dst.SetCellStr(dstSheet, cell, raw)
+	default:
+		if raw != "" {
+			if _, parseErr := strconv.ParseFloat(raw, 64); parseErr == nil {
+				err = dst.SetCellDefault(dstSheet, cell, raw)
+			} else {
+				err = dst.SetCellStr(dstSheet, cell, raw)
+			}
+		}
+	}
+	return err
+}
+
+// cellRefPattern matches an A1 cell reference with optional $ anchors
+var cellRefPattern = regexp.MustCompile(`^(\$?)([A-Za-z]{1,3})(\$?)([0-9]+)`)
+
+// shiftFormula moves the relative cell references of a formula by cols columns
+// and rows rows, as Excel does when a formula is copied to another cell. Anchored
+// ($) parts, strings, quoted sheet names and structured references are kept, and
+// a reference moved off the sheet becomes #REF!.
+func shiftFormula(formula string, cols, rows int) string {
+	if cols == 0 && rows == 0 {
+		return formula
+	}
+	isNameChar := func(ch byte) bool {
+		return ch == '_' || ch == '.' || ch == '$' || ch == '\\' ||
+			(ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9')
+	}
+	var b strings.Builder
+	for i := 0; i < len(formula); {
+		switch ch := formula[i]; {
+		case ch == '"' || ch == '\'':
+			// Strings and quoted sheet names end at an unpaired closing quote
+			end := i + 1
+			for end < len(formula) && (formula[end] != ch || (end+1 < len(formula) && formula[end+1] == ch)) {
+				if formula[end] == ch {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(formula))
+			b.WriteString(formula[i:end])
+			i = end
+			continue
+		case ch == '[':
+			end, depth := i, 0
+			for ; end < len(formula); end++ {
+				if formula[end] == '[' {
+					depth++
+				} else if formula[end] == ']' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			end = min(end+1, len(formula))
+			b.WriteString(formula[i:end])
+			i = end
+			continue
+		case i == 0 || !isNameChar(formula[i-1]):
+			m := cellRefPattern.FindStringSubmatch(formula[i:])
+			if m == nil {
+				break
+			}
+			next := i + len(m[0])
+			if next < len(formula) && (isNameChar(formula[next]) || formula[next] == '(' || formula[next] == '!') {
+				// A function call, a sheet name or a longer name
+				break
+			}
+			col, err := excelize.ColumnNameToNumber(m[2])
+			row, _ := strconv.Atoi(m[4])
+			if err != nil || row < 1 || row > excelize.TotalRows {
+				break
+			}
+			if m[1] == "" {
+				col += cols
+			}
+			if m[3] == "" {
+				row += rows
+			}
+			if name, err := excelize.ColumnNumberToName(col); err == nil && row >= 1 && row <= excelize.TotalRows {
+				b.WriteString(m[1] + name + m[3] + strconv.Itoa(row))
+			} else {
+				b.WriteString("#REF!")
+			}
+			i = next
+			continue
+		}
+		b.WriteByte(formula[i])
+		i++
+	}
+	return b.String()
+}
+
+// copyTableRow copies the cells of a table row with their types, formulas and
+// styles. The table starts at the 1-based column srcCol of srcSheet, and order
+// lists the table column written to each column of dstSheet from dstCol on.
+func copyTableRow(styles *styleCopier, srcSheet string, srcCol, srcRow int, dstSheet string, dstCol, dstRow int, order []int) error {
+	for i, j := range order {
+		srcCell, _ := excelize.CoordinatesToCellName(srcCol+j, srcRow)
+		dstCell, _ := excelize.CoordinatesToCellName(dstCol+i, dstRow)
+		if err := copyCell(styles.src, srcSheet, srcCell, styles.dst, dstSheet, dstCell); err != nil {
+			return fmt.Errorf("failed to copy cell %s: %w", srcCell, err)
+		}
+		style, err := styles.src.GetCellStyle(srcSheet, srcCell)
+		if err != nil {
+			return err
+		}
+		if style == 0 {
+			continue
+		}
+		styleID, err := styles.styleID(style)
+		if err != nil {
+			return fmt.Errorf("failed to copy style of cell %s: %w", srcCell, err)
+		}
+		if err := styles.dst.SetCellStyle(dstSheet, dstCell, dstCell, styleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isoDateLayouts are the ISO 8601 forms of the values of date cells (t="d")
+var isoDateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02T15:04", "2006-01-02"}
+
+// parseISODate parses the ISO 8601 value of a date cell
+func parseISODate(value string) (time.Time, bool) {
+	for _, layout := range isoDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
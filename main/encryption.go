@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/xuri/excelize/v2"
+)
+
+// oleSignature starts a compound file, which holds either an encrypted
+// workbook or a legacy binary (.xls) workbook
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// encryptedPackageStream is the UTF-16LE name of the compound file stream that
+// holds the package of an encrypted workbook
+var encryptedPackageStream = []byte("E\x00n\x00c\x00r\x00y\x00p\x00t\x00e\x00d\x00P\x00a\x00c\x00k\x00a\x00g\x00e\x00")
+
+// withFilePassword declares the optional password of a tool's encrypted workbook
+func withFilePassword() mcp.ToolOption {
+	return mcp.WithString("file_password",
+		mcp.Description("Password to open the workbook if it is encrypted; it stays encrypted with the same password when saved"),
+	)
+}
+
+// filePassword returns the file_password argument of a request
+func filePassword(request mcp.CallToolRequest) string {
+	password, _ := request.Params.Arguments["file_password"].(string)
+	return password
+}
+
+// openWorkbook opens a workbook, decrypting it with password if it is encrypted.
+// Saving the workbook encrypts it again with the same password. The password is
+// ignored for workbooks that are not encrypted, so that they are not encrypted on save.
+func openWorkbook(path, password string) (*excelize.File, error) {
+	compound, encrypted, err := inspectCompoundFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !compound {
+		return excelize.OpenFile(path)
+	}
+	if !encrypted {
+		return nil, errors.New("the file is a legacy .xls workbook, which is not supported; save it as .xlsx")
+	}
+	if password == "" {
+		return nil, errors.New("the workbook is encrypted; provide file_password")
+	}
+	f, err := excelize.OpenFile(path, excelize.Options{Password: password})
+	switch {
+	case errors.Is(err, excelize.ErrWorkbookPassword):
+		return nil, errors.New("file_password is not correct")
+	case errors.Is(err, excelize.ErrWorkbookFileFormat):
+		// excelize reports any decryption failure, such as an unsupported
+		// encryption method, as an unsupported file format
+		return nil, fmt.Errorf("failed to decrypt the workbook: %w", err)
+	case err != nil:
+		return nil, err
+	}
+	return f, nil
+}
+
+// isEncryptedFile reports whether the file at path is an encrypted workbook
+func isEncryptedFile(path string) bool {
+	_, encrypted, _ := inspectCompoundFile(path)
+	return encrypted
+}
+
+// inspectCompoundFile reports whether the file at path is a compound file and,
+// if so, whether it holds an encrypted workbook rather than a legacy .xls one
+func inspectCompoundFile(path string) (compound, encrypted bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, false, err
+	}
+	defer file.Close()
+	header := make([]byte, len(oleSignature))
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header, oleSignature) {
+		// too short or not a compound file; let excelize report what it is
+		return false, false, nil
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return true, false, err
+	}
+	return true, bytes.Contains(content, encryptedPackageStream), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// hyperlinkColor is the font color Excel gives hyperlinks
+const hyperlinkColor = "0563C1"
+
+// hyperlinkInfo describes the hyperlink of a cell or range. Type is "url" for
+// external targets and "location" for places in the workbook.
+type hyperlinkInfo struct {
+	Sheet   string `json:"sheet"`
+	Cell    string `json:"cell"`
+	Type    string `json:"type"`
+	Target  string `json:"target"`
+	Display string `json:"display,omitempty"`
+	Tooltip string `json:"tooltip,omitempty"`
+}
+
+// worksheetHyperlinks is the hyperlinks element of a worksheet part
+type worksheetHyperlinks struct {
+	Hyperlinks []struct {
+		Ref      string `xml:"ref,attr"`
+		RID      string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		Location string `xml:"location,attr"`
+		Display  string `xml:"display,attr"`
+		Tooltip  string `xml:"tooltip,attr"`
+	} `xml:"hyperlinks>hyperlink"`
+}
+
+// listHyperlinks reads the hyperlinks of a worksheet as stored in the file,
+// since excelize does not return their display text and tooltip
+func listHyperlinks(f *excelize.File, sheet string) ([]hyperlinkInfo, error) {
+	part, ok := sheetParts(f)[sheet]
+	if !ok || part.Path == "" {
+		return nil, fmt.Errorf("worksheet '%s' not found", sheet)
+	}
+	content, err := readWorksheetPart(f, sheet)
+	if err != nil {
+		return nil, err
+	}
+	links := []hyperlinkInfo{}
+	var parsed worksheetHyperlinks
+	if err := xml.Unmarshal(content, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse worksheet '%s': %w", sheet, err)
+	}
+	targets := make(map[string]string)
+	for _, rel := range readRelationships(f, part.Path).Relationships {
+		targets[rel.ID] = rel.Target
+	}
+	for _, link := range parsed.Hyperlinks {
+		info := hyperlinkInfo{
+			Sheet:   sheet,
+			Cell:    link.Ref,
+			Type:    "location",
+			Target:  link.Location,
+			Display: link.Display,
+			Tooltip: link.Tooltip,
+		}
+		if link.RID != "" {
+			info.Type, info.Target = "url", targets[link.RID]
+			if link.Location != "" {
+				info.Target += "#" + link.Location
+			}
+		}
+		links = append(links, info)
+	}
+	return links, nil
+}
+
+// checkLinkLocation verifies that a location in the workbook, such as
+// "Sheet2!A1", "'My Sheet'!B4" or a defined name, exists
+func checkLinkLocation(f *excelize.File, sheet, location string) error {
+	if refSheet, _, found := strings.Cut(location, "!"); found {
+		refSheet = strings.ReplaceAll(strings.Trim(refSheet, "'"), "''", "'")
+		if index, _ := f.GetSheetIndex(refSheet); index == -1 {
+			return fmt.Errorf("sheet '%s' of location %q not found", refSheet, location)
+		}
+		return nil
+	}
+	if _, _, ok := resolveDefinedName(f, sheet, location); !ok {
+		return fmt.Errorf("location %q must be a reference such as 'Sheet2!A1' or a defined name", location)
+	}
+	return nil
+}
+
+// styleAsHyperlink gives a cell the blue, underlined font of a hyperlink while keeping the rest of its style
+func styleAsHyperlink(f *excelize.File, sheet, cell string) error {
+	styleID, err := f.GetCellStyle(sheet, cell)
+	if err != nil {
+		return err
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		return err
+	}
+	if style.Font == nil {
+		style.Font = &excelize.Font{}
+	}
+	style.Font.Color = hyperlinkColor
+	style.Font.Underline = "single"
+	if styleID, err = f.NewStyle(style); err != nil {
+		return err
+	}
+	return f.SetCellStyle(sheet, cell, cell, styleID)
+}
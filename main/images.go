@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// pictureInsertTypes names the ways a picture can be placed in a worksheet
+var pictureInsertTypes = map[excelize.PictureInsertType]string{
+	excelize.PictureInsertTypePlaceOverCells: "over_cells",
+	excelize.PictureInsertTypePlaceInCell:    "in_cell",
+	excelize.PictureInsertTypeIMAGE:          "image_function",
+	excelize.PictureInsertTypeDISPIMG:        "dispimg_function",
+}
+
+// imageMIMETypes maps picture formats to the MIME types of MCP image content
+var imageMIMETypes = map[string]string{
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+	"bmp":  "image/bmp",
+	"tif":  "image/tiff",
+	"tiff": "image/tiff",
+	"ico":  "image/x-icon",
+	"svg":  "image/svg+xml",
+	"emf":  "image/emf",
+	"wmf":  "image/wmf",
+}
+
+// sheetPicture is a picture together with the cell it is anchored to
+type sheetPicture struct {
+	Sheet   string
+	Cell    string
+	Picture excelize.Picture
+}
+
+// imageInfo describes a picture without its data
+type imageInfo struct {
+	Sheet      string `json:"sheet"`
+	Cell       string `json:"cell"`
+	Format     string `json:"format"`
+	InsertType string `json:"insert_type"`
+	AltText    string `json:"alt_text,omitempty"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	SizeBytes  int    `json:"size_bytes"`
+	Path       string `json:"path,omitempty"`
+}
+
+// newImageInfo describes a picture. Width and height in pixels are included
+// for formats that can be decoded, such as PNG, JPEG and GIF.
+func newImageInfo(p sheetPicture) imageInfo {
+	info := imageInfo{
+		Sheet:      p.Sheet,
+		Cell:       p.Cell,
+		Format:     strings.TrimPrefix(strings.ToLower(p.Picture.Extension), "."),
+		InsertType: pictureInsertTypes[p.Picture.InsertType],
+		SizeBytes:  len(p.Picture.File),
+	}
+	if p.Picture.Format != nil {
+		info.AltText = p.Picture.Format.AltText
+	}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(p.Picture.File)); err == nil {
+		info.Width, info.Height = config.Width, config.Height
+	}
+	return info
+}
+
+// collectPictures returns every picture on the given sheets in cell order
+func collectPictures(f *excelize.File, sheets []string) ([]sheetPicture, error) {
+	var pictures []sheetPicture
+	for _, sheet := range sheets {
+		cells, err := f.GetPictureCells(sheet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pictures of '%s': %w", sheet, err)
+		}
+		for _, cell := range cells {
+			cellPictures, err := f.GetPictures(sheet, cell)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read pictures of '%s' at %s: %w", sheet, cell, err)
+			}
+			for _, pic := range cellPictures {
+				pictures = append(pictures, sheetPicture{Sheet: sheet, Cell: cell, Picture: pic})
+			}
+		}
+	}
+	return pictures, nil
+}
+
+// checkMediaName guards against the media naming of excelize v2.10.0, whose
+// addMedia (picture.go) names a new image "xl/media/image<N+1>" where N is the
+// number of media parts, replacing any part of that name. Once a picture has
+// been deleted, N+1 can be the number of an image that is still in use. Data
+// identical to an existing image reuses that part and is always safe.
+func checkMediaName(f *excelize.File, data []byte) error {
+	count, reused := 0, false
+	f.Pkg.Range(func(key, value interface{}) bool {
+		if strings.Contains(key.(string), "xl/media/image") {
+			count++
+			existing, _ := value.([]byte)
+			reused = reused || bytes.Equal(existing, data)
+		}
+		return true
+	})
+	if reused {
+		return nil
+	}
+	next, taken := fmt.Sprintf("xl/media/image%d.", count+1), ""
+	f.Pkg.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), next) {
+			taken = key.(string)
+			return false
+		}
+		return true
+	})
+	if taken != "" {
+		return fmt.Errorf("the new image would replace %s, because the image numbers have a gap left by a deleted image; "+
+			"save the workbook in Excel to renumber its images and try again", taken)
+	}
+	return nil
+}
+
+// decodeImageData decodes a base64 image payload, which may be a data URI such as
+// "data:image/png;base64,...". The format is taken from the data URI if not given.
+func decodeImageData(payload, format string) ([]byte, string, error) {
+	if rest, ok := strings.CutPrefix(payload, "data:"); ok {
+		header, data, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return nil, "", fmt.Errorf("image data URI must be base64 encoded")
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.TrimSuffix(header, ";base64"), "image/")
+			format = strings.TrimSuffix(format, "+xml")
+		}
+		payload = data
+	}
+	if format == "" {
+		return nil, "", fmt.Errorf("image_format is required with image_base64, e.g. 'png'")
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid base64 image data: %w", err)
+	}
+	return data, "." + strings.TrimPrefix(strings.ToLower(format), "."), nil
+}
+
+// readImageFile reads an image file and returns its data and extension
+func readImageFile(path string) ([]byte, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image: %w", err)
+	}
+	return data, filepath.Ext(path), nil
+}
+
+// imageFilePaths names the files that extracted pictures are written to in dir:
+// the sheet name and anchor cell, with a counter for further pictures at the same cell
+func imageFilePaths(pictures []sheetPicture, dir string) []string {
+	paths := make([]string, len(pictures))
+	seen := make(map[string]int)
+	for i, p := range pictures {
+		base := invalidFileNameChars.Replace(p.Sheet) + "_" + p.Cell
+		seen[base]++
+		if n := seen[base]; n > 1 {
+			base += fmt.Sprintf("_%d", n)
+		}
+		paths[i] = filepath.Join(dir, base+strings.ToLower(p.Picture.Extension))
+	}
+	return paths
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/xuri/excelize/v2"
 	"log"
+	"os"
 	"strings"
 )
 
@@ -28,6 +30,9 @@ func main() {
 			mcp.Required(),
 			mcp.Description("Path where to create the new Excel file"),
 		),
+		mcp.WithString("new_password",
+			mcp.Description("Encrypt the new workbook with this password (default: not encrypted)"),
+		),
 	)
 
 	s.AddTool(createWorkbookTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -36,10 +41,12 @@ func main() {
 			return nil, errors.New("filepath must be a string")
 		}
 
+		newPassword, _ := request.Params.Arguments["new_password"].(string)
+
 		f := excelize.NewFile()
 		defer f.Close()
 
-		if err := f.SaveAs(filepath); err != nil {
+		if err := f.SaveAs(filepath, excelize.Options{Password: newPassword}); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
 		}
 
@@ -53,18 +60,45 @@ func main() {
 			mcp.Required(),
 			mcp.Description("Path to the Excel file"),
 		),
+		withFilePassword(),
 		mcp.WithString("sheet_name",
-			mcp.Required(),
-			mcp.Description("Name of the worksheet to write to"),
+			mcp.Description("Name of the worksheet to write to (optional when start_cell is a defined name or table is given)"),
 		),
 		mcp.WithArray("data",
 			mcp.Required(),
 			mcp.Description("List of lists containing data to write (sublists are rows)"),
 		),
 		mcp.WithString("start_cell",
-			mcp.Description("Cell to start writing to (default: A1)"),
+			mcp.Description("Cell or defined name to start writing to (default: A1). "+
+				"With append, the column to write to and the first row to use when the columns are empty"),
 			mcp.DefaultString("A1"), // Corrected: Using DefaultString
 		),
+		mcp.WithBoolean("append",
+			mcp.Description("Write the rows below the last non-empty row of append_columns instead of at start_cell, "+
+				"e.g. to add entries to a log sheet (default: false)"),
+		),
+		mcp.WithString("append_columns",
+			mcp.Description("With append, the columns searched for the last non-empty row, e.g. 'A:D' "+
+				"(default: the columns written to)"),
+		),
+		mcp.WithString("table",
+			mcp.Description("Name of a table to append the rows to, below its last non-empty row. "+
+				"The table is extended to include them; sheet_name and start_cell are not needed"),
+		),
+		mcp.WithArray("column_types",
+			mcp.Description("Type of each column written, starting at the first column, so that values are stored "+
+				"as proper Excel types. Each item is a type name or an object {\"type\", \"layout\", \"number_format\"}. "+
+				"Types: 'auto' (default, values as given), 'text' (kept as text even if it looks like a number, e.g. IDs "+
+				"and codes with leading zeros), 'number' (numeric strings become numbers), 'date' (ISO 8601 strings, "+
+				"strings in 'layout' such as 'DD/MM/YYYY' or 'MM/DD/YYYY HH:mm', or Excel serial numbers), "+
+				"'boolean' (true/false, yes/no, 1/0) and 'formula' (e.g. '=SUM(A2:C2)'). "+
+				"Typed cells get a default number format: 'yyyy-mm-dd' for dates, 'yyyy-mm-dd hh:mm:ss' for date-times, "+
+				"'@' for text and '0' for whole numbers of 12 or more digits; 'number_format' overrides it. "+
+				"Example: [\"text\", {\"type\": \"date\", \"layout\": \"DD/MM/YYYY\"}, \"number\", \"boolean\"]"),
+		),
+		mcp.WithNumber("header_rows",
+			mcp.Description("Number of leading rows of data written as they are, without column_types (default: 0)"),
+		),
 	)
 
 	s.AddTool(writeDataTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -73,10 +107,7 @@ func main() {
 			return nil, errors.New("filepath must be a string")
 		}
 
-		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
-		if !ok {
-			return nil, errors.New("sheet_name must be a string")
-		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
 
 		dataInterface, ok := request.Params.Arguments["data"].([]interface{})
 		if !ok {
@@ -97,39 +128,112 @@ func main() {
 		if !ok {
 			startCell = "A1" // Default value
 		}
+		appendRows, _ := request.Params.Arguments["append"].(bool)
+		appendColumns, _ := request.Params.Arguments["append_columns"].(string)
+		tableName, _ := request.Params.Arguments["table"].(string)
 
-		f, err := excelize.OpenFile(filepath)
+		// The width of the widest row
+		width := 0
+		for _, rowData := range data {
+			width = max(width, len(rowData))
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
 		}
 		defer f.Close()
 
-		// Create the sheet if it doesn't exist
-		index, err := f.GetSheetIndex(sheetName)
-		if err != nil || index == -1 {
-			f.NewSheet(sheetName)
+		var col, row int
+		var table *sheetTable
+		if tableName != "" {
+			// Append below the last row of the table
+			if table, err = findTable(f, sheetName, tableName); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			sheetName = table.Sheet
+			if col, _, _, _, err = parseRangeRef(table.Ref); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid range of table '%s': %v", table.Name, err)), nil
+			}
+			if row, err = tableAppendRow(f, table, width, len(data)); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		} else {
+			// Resolve a defined name to its sheet and top-left cell
+			sheetName, startCell, _, err = resolveCellRef(f, sheetName, startCell)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			// Create the sheet if it doesn't exist
+			index, err := f.GetSheetIndex(sheetName)
+			if err != nil || index == -1 {
+				f.NewSheet(sheetName)
+			}
+
+			// Get starting coordinates from the cell reference
+			col, row, err = excelize.CellNameToCoordinates(startCell)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid start cell: %v", err)), nil
+			}
+
+			// Move below the last non-empty row of the columns searched
+			if appendRows {
+				col1, col2 := col, col+max(width, 1)-1
+				if appendColumns != "" {
+					if col1, col2, err = parseColumnRange(appendColumns); err != nil {
+						return mcp.NewToolResultError(err.Error()), nil
+					}
+				}
+				last, err := lastUsedRow(f, sheetName, col1, col2, 0)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to find the last row: %v", err)), nil
+				}
+				row = max(row, last+1)
+			}
 		}
 
-		// Get starting coordinates from the cell reference
-		col, row, err := excelize.CellNameToCoordinates(startCell)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("invalid start cell: %v", err)), nil
+		var types []columnType
+		if typesArg, ok := request.Params.Arguments["column_types"].([]interface{}); ok {
+			if types, err = parseColumnTypes(typesArg); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+		headerRows := 0
+		if n, ok := request.Params.Arguments["header_rows"].(float64); ok && n > 0 {
+			headerRows = int(n)
 		}
 
-		// Write all rows
-		for i, rowData := range data {
-			// Calculate current row number (1-based)
-			currentRow := row + i
+		// Write all rows, converting the values of typed columns
+		if len(types) > 0 {
+			if err := writeTypedRows(f, sheetName, col, row, data, types, headerRows); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to write data: %v", err)), nil
+			}
+		} else {
+			for i, rowData := range data {
+				// Calculate current row number (1-based)
+				currentRow := row + i
 
-			// Convert row number back to cell reference (A2, A3, etc.)
-			cellName, err := excelize.CoordinatesToCellName(col, currentRow)
-			if err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("failed to calculate cell position: %v", err)), nil
+				// Convert row number back to cell reference (A2, A3, etc.)
+				cellName, err := excelize.CoordinatesToCellName(col, currentRow)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to calculate cell position: %v", err)), nil
+				}
+
+				// Write the current row
+				if err := f.SetSheetRow(sheetName, cellName, &rowData); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to write row %d: %v", i+1, err)), nil
+				}
 			}
+		}
 
-			// Write the current row
-			if err := f.SetSheetRow(sheetName, cellName, &rowData); err != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("failed to write row %d: %v", i+1, err)), nil
+		// Extend the table over the appended rows
+		if table != nil && len(data) > 0 {
+			col1, row1, col2, row2, _ := parseRangeRef(table.Ref)
+			ref, _ := excelize.CoordinatesToCellName(col1, row1)
+			end, _ := excelize.CoordinatesToCellName(col2, max(row2, row+len(data)-1))
+			if err := resizeTable(f, table, ref+":"+end); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to extend table '%s': %v", table.Name, err)), nil
 			}
 		}
 
@@ -138,7 +242,19 @@ func main() {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(fmt.Sprintf("Successfully wrote %d rows to Excel", len(data))), nil
+		if !appendRows && table == nil {
+			return mcp.NewToolResultText(fmt.Sprintf("Successfully wrote %d rows to Excel", len(data))), nil
+		}
+		if len(data) == 0 || width == 0 {
+			return mcp.NewToolResultText("No rows to append"), nil
+		}
+		first, _ := excelize.CoordinatesToCellName(col, row)
+		last, _ := excelize.CoordinatesToCellName(col+width-1, row+len(data)-1)
+		result := fmt.Sprintf("Successfully appended %d rows to '%s' at %s:%s", len(data), sheetName, first, last)
+		if table != nil {
+			result += fmt.Sprintf("; table '%s' now spans %s", table.Name, table.Ref)
+		}
+		return mcp.NewToolResultText(result), nil
 	})
 
 	// Tool 3: read_data_from_excel
@@ -148,10 +264,19 @@ func main() {
 			mcp.Required(),
 			mcp.Description("Path to the Excel file"),
 		),
+		withFilePassword(),
 		mcp.WithString("sheet_name",
 			mcp.Required(),
 			mcp.Description("Name of the worksheet to read from"),
 		),
+		mcp.WithBoolean("include_comments",
+			mcp.Description("Return {\"data\": [...], \"comments\": [...]} with the cell comments of the sheet "+
+				"instead of the rows alone (default: false)"),
+		),
+		mcp.WithBoolean("include_hyperlinks",
+			mcp.Description("Return {\"data\": [...], \"hyperlinks\": [...]} with the hyperlink targets of the sheet "+
+				"instead of the rows alone; may be combined with include_comments (default: false)"),
+		),
 	)
 
 	s.AddTool(readDataTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -165,7 +290,7 @@ func main() {
 			return nil, errors.New("sheet_name must be a string")
 		}
 
-		f, err := excelize.OpenFile(filepath)
+		f, err := openWorkbook(filepath, filePassword(request))
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
 		}
@@ -183,8 +308,32 @@ func main() {
 			data = append(data, row)
 		}
 
+		var result interface{} = data
+		extras := map[string]interface{}{"data": data}
+		if includeComments, _ := request.Params.Arguments["include_comments"].(bool); includeComments {
+			sheetComments, err := f.GetComments(sheetName)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read comments: %v", err)), nil
+			}
+			comments := make([]commentInfo, 0, len(sheetComments))
+			for _, c := range sheetComments {
+				comments = append(comments, newCommentInfo(sheetName, c))
+			}
+			extras["comments"] = comments
+		}
+		if includeHyperlinks, _ := request.Params.Arguments["include_hyperlinks"].(bool); includeHyperlinks {
+			links, err := listHyperlinks(f, sheetName)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read hyperlinks: %v", err)), nil
+			}
+			extras["hyperlinks"] = links
+		}
+		if len(extras) > 1 {
+			result = extras
+		}
+
 		// Return as properly formatted JSON
-		jsonData, err := json.Marshal(data)
+		jsonData, err := json.Marshal(result)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal data to JSON: %v", err)), nil
 		}
@@ -199,10 +348,14 @@ func main() {
 			mcp.Required(),
 			mcp.Description("Path to the Excel file"),
 		),
+		withFilePassword(),
 		mcp.WithString("sheet_name",
 			mcp.Required(),
 			mcp.Description("Name of the worksheet to create"),
 		),
+		mcp.WithNumber("position",
+			mcp.Description("Zero-based tab position to insert the worksheet at (default: after the last sheet)"),
+		),
 	)
 	s.AddTool(createWorksheetTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		filepath, ok := request.Params.Arguments["filepath"].(string)
@@ -213,7 +366,7 @@ func main() {
 		if !ok {
 			return nil, errors.New("sheet_name must be a string")
 		}
-		f, err := excelize.OpenFile(filepath)
+		f, err := openWorkbook(filepath, filePassword(request))
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
 		}
@@ -223,6 +376,11 @@ func main() {
 			return mcp.NewToolResultError(fmt.Sprintf("worksheet '%s' already exists", sheetName)), nil
 		}
 		f.NewSheet(sheetName)
+		if position, ok := request.Params.Arguments["position"].(float64); ok {
+			if err := moveSheet(f, sheetName, int(position)); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to position worksheet: %v", err)), nil
+			}
+		}
 		if err := f.Save(); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
 		}
@@ -236,6 +394,7 @@ func main() {
 			mcp.Required(),
 			mcp.Description("Path to the Excel file"),
 		),
+		withFilePassword(),
 		mcp.WithString("sheet_name",
 			mcp.Required(),
 			mcp.Description("Name of the worksheet to delete"),
@@ -250,7 +409,7 @@ func main() {
 		if !ok {
 			return nil, errors.New("sheet_name must be a string")
 		}
-		f, err := excelize.OpenFile(filepath)
+		f, err := openWorkbook(filepath, filePassword(request))
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
 		}
@@ -275,6 +434,7 @@ func main() {
 			mcp.Required(),
 			mcp.Description("Path to the Excel file"),
 		),
+		withFilePassword(),
 		mcp.WithString("old_name",
 			mcp.Required(),
 			mcp.Description("Current name of the worksheet"),
@@ -297,7 +457,7 @@ func main() {
 		if !ok {
 			return nil, errors.New("new_name must be a string")
 		}
-		f, err := excelize.OpenFile(filepath)
+		f, err := openWorkbook(filepath, filePassword(request))
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
 		}
@@ -326,8 +486,10 @@ func main() {
 			mcp.Required(),
 			mcp.Description("Path to the Excel file"),
 		),
+		withFilePassword(),
 		mcp.WithBoolean("include_ranges",
-			mcp.Description("Whether to include range information (optional)"),
+			mcp.Description("Whether to include the used range and dimensions of each sheet (optional). "+
+				"The used range is the bounding box of all non-empty cells"),
 		),
 	)
 	s.AddTool(getWorkbookMetadataTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -336,39 +498,37 @@ func main() {
 			return nil, errors.New("filepath must be a string")
 		}
 		includeRanges, _ := request.Params.Arguments["include_ranges"].(bool)
-		f, err := excelize.OpenFile(filepath)
+		f, err := openWorkbook(filepath, filePassword(request))
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
 		}
 		defer f.Close()
 		// Basic metadata structure
 		metadata := struct {
-			Sheets    []string              `json:"sheets"`
-			Ranges    map[string][][]string `json:"ranges,omitempty"`
-			NumSheets int                   `json:"num_sheets"`
+			Sheets     []string                    `json:"sheets"`
+			Ranges     map[string][][]string       `json:"ranges,omitempty"`
+			Dimensions map[string]*sheetDimensions `json:"dimensions,omitempty"`
+			NumSheets  int                         `json:"num_sheets"`
 		}{
 			Sheets:    f.GetSheetList(),
 			NumSheets: len(f.GetSheetList()),
 		}
 		if includeRanges {
 			metadata.Ranges = make(map[string][][]string)
+			metadata.Dimensions = make(map[string]*sheetDimensions)
 			for _, sheet := range metadata.Sheets {
-				// Get all non-empty cells in the sheet
-				cols, err := f.GetCols(sheet)
-				if err != nil {
+				if !isWorksheet(f, sheet) {
 					continue
 				}
-				if len(cols) > 0 {
-					rows, err := f.GetRows(sheet)
-					if err != nil {
-						continue
-					}
-					// Calculate used range
-					if len(rows) > 0 {
-						startCell, _ := excelize.CoordinatesToCellName(1, 1)
-						endCell, _ := excelize.CoordinatesToCellName(len(cols), len(rows))
-						metadata.Ranges[sheet] = [][]string{{startCell, endCell}}
-					}
+				dims, err := getSheetDimensions(f, sheet)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to read sheet '%s': %v", sheet, err)), nil
+				}
+				metadata.Dimensions[sheet] = dims
+				// Calculate used range
+				if dims.UsedRange != "" {
+					bounds := strings.Split(dims.UsedRange, ":")
+					metadata.Ranges[sheet] = [][]string{{bounds[0], bounds[1]}}
 				}
 			}
 		}
@@ -384,7 +544,7 @@ func main() {
 	formatRangeTool := mcp.NewTool("format_range",
 		mcp.WithDescription("Apply comprehensive formatting to a range of cells in an Excel worksheet. "+
 			"Supports text formatting, borders, alignment, number formats, and more. "+
-			"All parameters are optional except filepath, sheet_name and start_cell."),
+			"All parameters are optional except filepath and start_cell."),
 
 		// Required Parameters
 		mcp.WithString("filepath",
@@ -392,15 +552,17 @@ func main() {
 			mcp.Description("Absolute or relative path to the Excel (.xlsx) file "+
 				"Example: 'reports/Q3_results.xlsx' or 'C:\\reports\\sales.xlsx'"),
 		),
+		withFilePassword(),
 		mcp.WithString("sheet_name",
-			mcp.Required(),
 			mcp.Description("Name of the worksheet where formatting should be applied. "+
+				"Required unless start_cell is a defined name. "+
 				"Example: 'Sheet1', 'Sales Data', or 'Q3 Report'"),
 		),
 		mcp.WithString("start_cell",
 			mcp.Required(),
-			mcp.Description("Top-left cell of the target range in A1 notation. "+
-				"Example: 'A1' for single cell or 'B2' for range start"),
+			mcp.Description("Top-left cell of the target range in A1 notation, or a defined name "+
+				"whose whole range is formatted when end_cell is omitted. "+
+				"Example: 'A1' for single cell, 'B2' for range start or 'SalesTotals'"),
 		),
 
 		// Optional Parameters
@@ -475,10 +637,12 @@ func main() {
 		),
 		mcp.WithBoolean("merge_cells",
 			mcp.Description("Set to true to merge the specified cell range into one cell. "+
-				"Note: Contents will be preserved from top-left cell only."),
+				"Note: Contents will be preserved from top-left cell only; the values of other cells are "+
+				"discarded and overlapping merged areas are removed. Use check_merge to see what would be lost."),
 		),
 		mcp.WithBoolean("protection_lock",
-			mcp.Description("Set to true to lock cells (requires sheet protection to take effect)"),
+			mcp.Description("Set to true to lock cells or false to unlock them, e.g. input cells; "+
+				"takes effect once the sheet is protected with protect_sheet"),
 		),
 		mcp.WithString("conditional_format",
 			mcp.Description("JSON string defining conditional formatting rules (advanced usage)"),
@@ -491,23 +655,16 @@ func main() {
 		if !ok || filepath == "" {
 			return nil, errors.New("filepath is required and must be a non-empty string")
 		}
-		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
-		if !ok || sheetName == "" {
-			return nil, errors.New("sheet_name is required and must be a non-empty string")
-		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
 		startCell, ok := request.Params.Arguments["start_cell"].(string)
 		if !ok || startCell == "" {
 			return nil, errors.New("start_cell is required and must be a non-empty string")
 		}
 
-		// Handle optional end_cell (default to start_cell if not provided)
 		endCell, _ := request.Params.Arguments["end_cell"].(string)
-		if endCell == "" {
-			endCell = startCell
-		}
 
 		// Open the Excel file
-		f, err := excelize.OpenFile(filepath)
+		f, err := openWorkbook(filepath, filePassword(request))
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
 		}
@@ -517,6 +674,20 @@ func main() {
 			}
 		}()
 
+		// Resolve a defined name to its sheet and range
+		sheetName, startCell, nameEndCell, err := resolveCellRef(f, sheetName, startCell)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Handle optional end_cell (default to the defined name's range or start_cell)
+		if endCell == "" {
+			endCell = nameEndCell
+		}
+		if endCell == "" {
+			endCell = startCell
+		}
+
 		// Initialize style with defaults
 		style := &excelize.Style{
 			Font:      &excelize.Font{},
@@ -609,11 +780,27 @@ func main() {
 			return mcp.NewToolResultError(fmt.Sprintf("failed to apply style: %v", err)), nil
 		}
 
-		// Handle merge cells
+		// Handle merge cells, reporting any contents the merge discards
+		var mergeWarning string
 		if merge, ok := request.Params.Arguments["merge_cells"].(bool); ok && merge {
+			check, err := checkMerge(f, sheetName, startCell+":"+endCell)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to check merge: %v", err)), nil
+			}
 			if err := f.MergeCell(sheetName, startCell, endCell); err != nil {
 				return mcp.NewToolResultError(fmt.Sprintf("failed to merge cells: %v", err)), nil
 			}
+			if len(check.DiscardedCells) > 0 {
+				cells := make([]string, len(check.DiscardedCells))
+				for i, c := range check.DiscardedCells {
+					cells[i] = c.Cell
+				}
+				mergeWarning += fmt.Sprintf(". Warning: merging hid the contents of %s", strings.Join(cells, ", "))
+			}
+			if len(check.OverlappingMerges) > 0 {
+				mergeWarning += fmt.Sprintf(". Warning: merging replaced the merged areas %s",
+					strings.Join(check.OverlappingMerges, ", "))
+			}
 		}
 
 		// Save changes
@@ -622,42 +809,3659 @@ func main() {
 		}
 
 		return mcp.NewToolResultText(
-			fmt.Sprintf("Successfully formatted range %s:%s in sheet '%s'",
-				startCell, endCell, sheetName),
+			fmt.Sprintf("Successfully formatted range %s:%s in sheet '%s'%s",
+				startCell, endCell, sheetName, mergeWarning),
 		), nil
 	})
 
-	// Start the stdio server
-	log.Println("Excel Tools Server starting...")
-	if err := server.ServeStdio(s); err != nil {
-		log.Fatalf("Server error: %v", err)
-	}
-}
+	// Tool 6: create_pivot_table
+	createPivotTableTool := mcp.NewTool("create_pivot_table",
+		mcp.WithDescription("Create a pivot table summarizing a source data range. "+
+			"The first row of the data range must contain the field (column) names."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("data_range",
+			mcp.Required(),
+			mcp.Description("Source data range including the header row. "+
+				"Example: 'Sheet1!A1:E31'"),
+		),
+		mcp.WithString("pivot_table_range",
+			mcp.Required(),
+			mcp.Description("Target location of the pivot table. The sheet must already exist. "+
+				"Example: 'Summary!A3:H20'"),
+		),
+		mcp.WithString("name",
+			mcp.Description("Name of the pivot table (optional)"),
+		),
+		mcp.WithArray("rows",
+			mcp.Description("Field names to use as row labels. Example: ['Region', 'Month']"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("columns",
+			mcp.Description("Field names to use as column labels. Example: ['Year']"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("filters",
+			mcp.Description("Field names to use as report filters. Example: ['Product']"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("data",
+			mcp.Required(),
+			mcp.Description("Value fields to aggregate. Each item is an object with "+
+				"'field' (required), 'function' ('sum','count','average','max','min', default 'sum') "+
+				"and 'name' (optional display name). "+
+				"Example: [{\"field\": \"Sales\", \"function\": \"sum\", \"name\": \"Total Sales\"}]"),
+			mcp.Items(map[string]interface{}{"type": "object"}),
+		),
+		mcp.WithBoolean("row_grand_totals",
+			mcp.Description("Show grand totals for rows (default: true)"),
+		),
+		mcp.WithBoolean("col_grand_totals",
+			mcp.Description("Show grand totals for columns (default: true)"),
+		),
+		mcp.WithString("style_name",
+			mcp.Description("Built-in pivot table style name. Example: 'PivotStyleLight16'"),
+		),
+	)
 
-var borderStyles = map[string]int{
-	"none":             -1,
-	"thin":             1,
-	"medium":           2,
-	"dashed":           3,
-	"dotted":           4,
-	"thick":            5,
-	"double":           6,
-	"hair":             7,
-	"mediumDashed":     8,
-	"dashDot":          9,
-	"mediumDashDot":    10,
-	"dashDotDot":       11,
-	"mediumDashDotDot": 12,
-	"slantDashDot":     13,
-}
-var patterns = map[string]int{
-	"none":       0,
-	"solid":      1,
-	"darkGray":   2,
-	"mediumGray": 3,
-	"lightGray":  4,
-	"gray125":    5,
-	"gray0625":   6,
+	s.AddTool(createPivotTableTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		dataRange, ok := request.Params.Arguments["data_range"].(string)
+		if !ok || dataRange == "" {
+			return nil, errors.New("data_range is required and must be a non-empty string")
+		}
+		pivotTableRange, ok := request.Params.Arguments["pivot_table_range"].(string)
+		if !ok || pivotTableRange == "" {
+			return nil, errors.New("pivot_table_range is required and must be a non-empty string")
+		}
+		name, _ := request.Params.Arguments["name"].(string)
+		styleName, _ := request.Params.Arguments["style_name"].(string)
+
+		rows, err := toStringSlice(request.Params.Arguments["rows"])
+		if err != nil {
+			return nil, fmt.Errorf("rows: %w", err)
+		}
+		columns, err := toStringSlice(request.Params.Arguments["columns"])
+		if err != nil {
+			return nil, fmt.Errorf("columns: %w", err)
+		}
+		filters, err := toStringSlice(request.Params.Arguments["filters"])
+		if err != nil {
+			return nil, fmt.Errorf("filters: %w", err)
+		}
+		dataInterface, ok := request.Params.Arguments["data"].([]interface{})
+		if !ok || len(dataInterface) == 0 {
+			return nil, errors.New("data must be a non-empty array")
+		}
+
+		opts := &excelize.PivotTableOptions{
+			DataRange:           dataRange,
+			PivotTableRange:     pivotTableRange,
+			Name:                name,
+			RowGrandTotals:      true,
+			ColGrandTotals:      true,
+			ShowDrill:           true,
+			ShowRowHeaders:      true,
+			ShowColHeaders:      true,
+			ShowLastColumn:      true,
+			PivotTableStyleName: styleName,
+		}
+		if v, ok := request.Params.Arguments["row_grand_totals"].(bool); ok {
+			opts.RowGrandTotals = v
+		}
+		if v, ok := request.Params.Arguments["col_grand_totals"].(bool); ok {
+			opts.ColGrandTotals = v
+		}
+		for _, field := range rows {
+			opts.Rows = append(opts.Rows, excelize.PivotTableField{Data: field, DefaultSubtotal: true})
+		}
+		for _, field := range columns {
+			opts.Columns = append(opts.Columns, excelize.PivotTableField{Data: field, DefaultSubtotal: true})
+		}
+		for _, field := range filters {
+			opts.Filter = append(opts.Filter, excelize.PivotTableField{Data: field})
+		}
+		for i, item := range dataInterface {
+			dataField, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("data[%d] must be an object", i)
+			}
+			field, _ := dataField["field"].(string)
+			if field == "" {
+				return nil, fmt.Errorf("data[%d].field is required", i)
+			}
+			function, _ := dataField["function"].(string)
+			if function == "" {
+				function = "sum"
+			}
+			subtotal, exists := pivotSubtotals[strings.ToLower(function)]
+			if !exists {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid aggregation function: %s", function)), nil
+			}
+			displayName, _ := dataField["name"].(string)
+			opts.Data = append(opts.Data, excelize.PivotTableField{
+				Data:     field,
+				Name:     displayName,
+				Subtotal: subtotal,
+			})
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		if err := f.AddPivotTable(opts); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to create pivot table: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Pivot table created at %s from %s", pivotTableRange, dataRange)), nil
+	})
+
+	// Tool 7: list_pivot_tables
+	listPivotTablesTool := mcp.NewTool("list_pivot_tables",
+		mcp.WithDescription("List the pivot tables on a worksheet and their definitions"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Name of the worksheet containing the pivot tables"),
+		),
+	)
+
+	s.AddTool(listPivotTablesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		pivotTables, err := f.GetPivotTables(sheetName)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get pivot tables: %v", err)), nil
+		}
+
+		type pivotField struct {
+			Field    string `json:"field"`
+			Name     string `json:"name,omitempty"`
+			Function string `json:"function,omitempty"`
+		}
+		type pivotTable struct {
+			Name            string       `json:"name"`
+			DataRange       string       `json:"data_range"`
+			PivotTableRange string       `json:"pivot_table_range"`
+			Rows            []pivotField `json:"rows"`
+			Columns         []pivotField `json:"columns"`
+			Filters         []pivotField `json:"filters"`
+			Data            []pivotField `json:"data"`
+			RowGrandTotals  bool         `json:"row_grand_totals"`
+			ColGrandTotals  bool         `json:"col_grand_totals"`
+			StyleName       string       `json:"style_name,omitempty"`
+		}
+		toFields := func(fields []excelize.PivotTableField) []pivotField {
+			result := []pivotField{}
+			for _, field := range fields {
+				result = append(result, pivotField{
+					Field:    field.Data,
+					Name:     field.Name,
+					Function: strings.ToLower(field.Subtotal),
+				})
+			}
+			return result
+		}
+
+		result := []pivotTable{}
+		for _, pt := range pivotTables {
+			result = append(result, pivotTable{
+				Name:            pt.Name,
+				DataRange:       pt.DataRange,
+				PivotTableRange: pt.PivotTableRange,
+				Rows:            toFields(pt.Rows),
+				Columns:         toFields(pt.Columns),
+				Filters:         toFields(pt.Filter),
+				Data:            toFields(pt.Data),
+				RowGrandTotals:  pt.RowGrandTotals,
+				ColGrandTotals:  pt.ColGrandTotals,
+				StyleName:       pt.PivotTableStyleName,
+			})
+		}
+
+		jsonData, err := json.Marshal(result)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal pivot tables: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 8: query_range
+	queryRangeTool := mcp.NewTool("query_range",
+		mcp.WithDescription("Filter, group and aggregate a header-based table in a worksheet "+
+			"and return only the result. Use this instead of reading large sheets to compute totals."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Name of the worksheet containing the table (optional when range is a defined name)"),
+		),
+		mcp.WithString("range",
+			mcp.Description("Table range or defined name whose first row holds the column names. "+
+				"Example: 'A1:F5000' or 'SalesData'. Defaults to the whole sheet starting at A1"),
+		),
+		mcp.WithObject("query",
+			mcp.Description("Query specification. Fields (all optional): "+
+				"'filters': [{\"column\", \"op\", \"value\"}] combined with AND, op is one of "+
+				"'=','!=','>','>=','<','<=','contains','in','not_in','is_empty','not_empty'; "+
+				"'group_by': [column names]; "+
+				"'aggregates': [{\"column\", \"function\", \"as\"}] with function "+
+				"'sum','count','count_distinct','average','min','max' (column '*' allowed for count); "+
+				"'select': [column names] when not aggregating; "+
+				"'order_by': [{\"column\", \"desc\"}] referring to result columns; "+
+				"'limit': maximum number of result rows. "+
+				"Filter values match cells by type: a JSON number matches numbers, a string matches text and true/false match booleans. "+
+				"Example: {\"filters\": [{\"column\": \"Year\", \"op\": \"=\", \"value\": 2025}], "+
+				"\"group_by\": [\"Region\"], \"aggregates\": [{\"column\": \"Amount\", \"function\": \"sum\", \"as\": \"Revenue\"}], "+
+				"\"order_by\": [{\"column\": \"Revenue\", \"desc\": true}], \"limit\": 10}"),
+		),
+	)
+
+	s.AddTool(queryRangeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		cellRange, _ := request.Params.Arguments["range"].(string)
+
+		var spec querySpec
+		if query, ok := request.Params.Arguments["query"]; ok && query != nil {
+			queryJSON, err := json.Marshal(query)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query: %w", err)
+			}
+			if err := json.Unmarshal(queryJSON, &spec); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid query: %v", err)), nil
+			}
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, cellRange, err = resolveRangeRef(f, sheetName, cellRange)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		table, err := readTable(f, sheetName, cellRange)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read table: %v", err)), nil
+		}
+		result, err := runQuery(table, &spec)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to run query: %v", err)), nil
+		}
+
+		jsonData, err := json.Marshal(result)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal query result: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 9: sql_query
+	sqlQueryTool := mcp.NewTool("sql_query",
+		mcp.WithDescription("Run a SQL-like SELECT query over worksheet ranges and return the result as JSON "+
+			"or write it to a destination range. Supported syntax: "+
+			"SELECT [DISTINCT] cols | SUM/COUNT/AVG/MIN/MAX(col) [AS alias] "+
+			"FROM 'Sheet'!A1:F5000 [alias] [[INNER|LEFT] JOIN 'Other'!A1:C100 [alias] ON a.key = b.key] "+
+			"[WHERE conditions with =, <>, <, <=, >, >=, IN, LIKE, BETWEEN, IS [NOT] NULL, AND, OR, NOT] "+
+			"[GROUP BY cols] [ORDER BY cols [ASC|DESC]] [LIMIT n]. "+
+			"The first row of each range holds the column names; quote names with spaces as [Unit Price]."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("SQL query. Example: SELECT region, SUM(amount) AS total "+
+				"FROM 'Sales'!A1:F5000 WHERE year = 2025 GROUP BY region ORDER BY total DESC"),
+		),
+		mcp.WithString("destination_sheet",
+			mcp.Description("Worksheet to write the result to instead of returning it (created if missing)"),
+		),
+		mcp.WithString("destination_cell",
+			mcp.Description("Top-left cell of the written result (default: A1), or a defined name; "+
+				"a defined name writes the result to its sheet without destination_sheet"),
+			mcp.DefaultString("A1"),
+		),
+		mcp.WithBoolean("include_header",
+			mcp.Description("Write the column names as the first row of the destination range (default: true)"),
+		),
+	)
+
+	s.AddTool(sqlQueryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		query, ok := request.Params.Arguments["query"].(string)
+		if !ok || query == "" {
+			return nil, errors.New("query is required and must be a non-empty string")
+		}
+		destSheet, _ := request.Params.Arguments["destination_sheet"].(string)
+		destCell, ok := request.Params.Arguments["destination_cell"].(string)
+		if !ok || destCell == "" {
+			destCell = "A1"
+		}
+		includeHeader := true
+		if v, ok := request.Params.Arguments["include_header"].(bool); ok {
+			includeHeader = v
+		}
+
+		parsed, err := parseSQL(query)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid query: %v", err)), nil
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		result, err := runSQL(f, parsed)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to run query: %v", err)), nil
+		}
+
+		_, _, cellErr := excelize.CellNameToCoordinates(destCell)
+		if destSheet == "" && cellErr == nil {
+			jsonData, err := json.Marshal(result)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to marshal query result: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(jsonData)), nil
+		}
+
+		// Resolve a defined name to its sheet and top-left cell
+		destSheet, destCell, _, err = resolveCellRef(f, destSheet, destCell)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid destination cell: %v", err)), nil
+		}
+		col, row, _ := excelize.CellNameToCoordinates(destCell)
+		if index, err := f.GetSheetIndex(destSheet); err != nil || index == -1 {
+			if _, err := f.NewSheet(destSheet); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to create worksheet: %v", err)), nil
+			}
+		}
+		var data [][]interface{}
+		if includeHeader {
+			header := make([]interface{}, len(result.Columns))
+			for i, name := range result.Columns {
+				header[i] = name
+			}
+			data = append(data, header)
+		}
+		data = append(data, result.Rows...)
+		for i, rowData := range data {
+			cellName, err := excelize.CoordinatesToCellName(col, row+i)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to calculate cell position: %v", err)), nil
+			}
+			if err := f.SetSheetRow(destSheet, cellName, &rowData); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to write row %d: %v", i+1, err)), nil
+			}
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+
+		if len(data) == 0 || len(result.Columns) == 0 {
+			return mcp.NewToolResultText(fmt.Sprintf("Query returned no rows; nothing written to '%s'", destSheet)), nil
+		}
+		endCell, _ := excelize.CoordinatesToCellName(col+len(result.Columns)-1, row+len(data)-1)
+		return mcp.NewToolResultText(fmt.Sprintf("Wrote %d result rows to '%s'!%s:%s",
+			len(result.Rows), destSheet, destCell, endCell)), nil
+	})
+
+	// Tool 10: add_data_validation
+	addDataValidationTool := mcp.NewTool("add_data_validation",
+		mcp.WithDescription("Add a data validation rule (dropdown list, number/date/time bounds, "+
+			"text length or custom formula) to a range of cells"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Name of the worksheet (optional when range is a defined name)"),
+		),
+		mcp.WithString("range",
+			mcp.Required(),
+			mcp.Description("Cells the rule applies to, or a defined name. Multiple ranges are separated by spaces. "+
+				"Example: 'A2:A100', 'B2:B50 D2:D50' or 'StatusColumn'"),
+		),
+		mcp.WithString("type",
+			mcp.Required(),
+			mcp.Description("Validation type: 'list','whole','decimal','date','time','text_length','custom'"),
+			mcp.Enum("list", "whole", "decimal", "date", "time", "text_length", "custom"),
+		),
+		mcp.WithArray("list_items",
+			mcp.Description("Inline dropdown values for type 'list' (255 characters max in total). "+
+				"Example: ['Open', 'In Progress', 'Done']"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("list_source",
+			mcp.Description("Cell range holding the dropdown values for type 'list'. "+
+				"Example: '$E$1:$E$10' or 'Lists!$A$1:$A$50'"),
+		),
+		mcp.WithString("operator",
+			mcp.Description("Comparison for whole, decimal, date, time and text_length rules (default: 'between'). "+
+				"Options: 'between','not_between','equal','not_equal','greater_than',"+
+				"'greater_than_or_equal','less_than','less_than_or_equal'"),
+		),
+		mcp.WithString("minimum",
+			mcp.Description("Lower bound, or the single value for one-sided operators. "+
+				"Numbers, ISO dates ('2025-01-01') for date rules, 'HH:MM' for time rules, "+
+				"or a formula/cell reference"),
+		),
+		mcp.WithString("maximum",
+			mcp.Description("Upper bound for 'between' and 'not_between'"),
+		),
+		mcp.WithString("formula",
+			mcp.Description("Formula for type 'custom' that must evaluate to TRUE for valid input. "+
+				"Example: 'ISNUMBER(SEARCH(\"@\",A2))'"),
+		),
+		mcp.WithBoolean("allow_blank",
+			mcp.Description("Allow empty cells (default: true)"),
+		),
+		mcp.WithString("input_title",
+			mcp.Description("Title of the message shown when a cell is selected"),
+		),
+		mcp.WithString("input_message",
+			mcp.Description("Message shown when a cell is selected"),
+		),
+		mcp.WithString("error_title",
+			mcp.Description("Title of the alert shown when invalid data is entered"),
+		),
+		mcp.WithString("error_message",
+			mcp.Description("Alert shown when invalid data is entered"),
+		),
+		mcp.WithString("error_style",
+			mcp.Description("Alert style: 'stop' (default), 'warning' or 'information'"),
+		),
+	)
+
+	s.AddTool(addDataValidationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		sqref, ok := request.Params.Arguments["range"].(string)
+		if !ok || sqref == "" {
+			return nil, errors.New("range is required and must be a non-empty string")
+		}
+		validationType, ok := request.Params.Arguments["type"].(string)
+		if !ok {
+			return nil, errors.New("type must be a string")
+		}
+		dvType, exists := validationTypes[validationType]
+		if !exists {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid validation type: %s", validationType)), nil
+		}
+
+		allowBlank := true
+		if v, ok := request.Params.Arguments["allow_blank"].(bool); ok {
+			allowBlank = v
+		}
+		dv := excelize.NewDataValidation(allowBlank)
+
+		switch validationType {
+		case "list":
+			items, err := toStringSlice(request.Params.Arguments["list_items"])
+			if err != nil {
+				return nil, fmt.Errorf("list_items: %w", err)
+			}
+			source, _ := request.Params.Arguments["list_source"].(string)
+			switch {
+			case source != "":
+				dv.SetSqrefDropList(strings.TrimPrefix(source, "="))
+			case len(items) > 0:
+				if err := dv.SetDropList(items); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid list items: %v", err)), nil
+				}
+			default:
+				return mcp.NewToolResultError("list validation requires list_items or list_source"), nil
+			}
+		case "custom":
+			formula, _ := request.Params.Arguments["formula"].(string)
+			if formula == "" {
+				return mcp.NewToolResultError("custom validation requires a formula"), nil
+			}
+			dv.Type = "custom"
+			dv.Formula1 = formulaXMLEscaper.Replace(strings.TrimPrefix(formula, "="))
+		default:
+			operatorName, _ := request.Params.Arguments["operator"].(string)
+			if operatorName == "" {
+				operatorName = "between"
+			}
+			operator, exists := validationOperators[operatorName]
+			if !exists {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid operator: %s", operatorName)), nil
+			}
+			minimum, err := validationBound(request.Params.Arguments["minimum"], validationType)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid minimum: %v", err)), nil
+			}
+			maximum, err := validationBound(request.Params.Arguments["maximum"], validationType)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid maximum: %v", err)), nil
+			}
+			if minimum == "" {
+				return mcp.NewToolResultError(fmt.Sprintf("%s validation requires a minimum", validationType)), nil
+			}
+			if operatorName == "between" || operatorName == "not_between" {
+				if maximum == "" {
+					return mcp.NewToolResultError(fmt.Sprintf("operator %s requires a maximum", operatorName)), nil
+				}
+			} else {
+				maximum = ""
+			}
+			if err := dv.SetRange(minimum, maximum, dvType, operator); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid validation range: %v", err)), nil
+			}
+		}
+
+		inputTitle, _ := request.Params.Arguments["input_title"].(string)
+		inputMessage, _ := request.Params.Arguments["input_message"].(string)
+		if inputTitle != "" || inputMessage != "" {
+			dv.SetInput(inputTitle, inputMessage)
+		}
+		errorTitle, _ := request.Params.Arguments["error_title"].(string)
+		errorMessage, _ := request.Params.Arguments["error_message"].(string)
+		errorStyleName, _ := request.Params.Arguments["error_style"].(string)
+		if errorTitle != "" || errorMessage != "" || errorStyleName != "" {
+			if errorStyleName == "" {
+				errorStyleName = "stop"
+			}
+			errorStyle, exists := validationErrorStyles[errorStyleName]
+			if !exists {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid error style: %s", errorStyleName)), nil
+			}
+			dv.SetError(errorStyle, errorTitle, errorMessage)
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		// Resolve a defined name to its sheet and range
+		if sheetName, sqref, err = resolveSqref(f, sheetName, sqref); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		dv.Sqref = sqref
+
+		if err := f.AddDataValidation(sheetName, dv); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to add data validation: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Added %s validation to %s in sheet '%s'", validationType, sqref, sheetName)), nil
+	})
+
+	// Tool 11: list_data_validations
+	listDataValidationsTool := mcp.NewTool("list_data_validations",
+		mcp.WithDescription("List the data validation rules of a worksheet"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Name of the worksheet"),
+		),
+	)
+
+	s.AddTool(listDataValidationsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		dataValidations, err := f.GetDataValidations(sheetName)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get data validations: %v", err)), nil
+		}
+
+		type validationRule struct {
+			Range        string   `json:"range"`
+			Type         string   `json:"type"`
+			Operator     string   `json:"operator,omitempty"`
+			Formula1     string   `json:"formula1,omitempty"`
+			Formula2     string   `json:"formula2,omitempty"`
+			ListItems    []string `json:"list_items,omitempty"`
+			AllowBlank   bool     `json:"allow_blank"`
+			InputTitle   string   `json:"input_title,omitempty"`
+			InputMessage string   `json:"input_message,omitempty"`
+			ErrorTitle   string   `json:"error_title,omitempty"`
+			ErrorMessage string   `json:"error_message,omitempty"`
+			ErrorStyle   string   `json:"error_style,omitempty"`
+		}
+		rules := []validationRule{}
+		for _, dv := range dataValidations {
+			rule := validationRule{
+				Range:        dv.Sqref,
+				Type:         validationTypeName(dv.Type),
+				Operator:     validationOperatorName(dv.Operator),
+				Formula1:     dv.Formula1,
+				Formula2:     dv.Formula2,
+				AllowBlank:   dv.AllowBlank,
+				InputTitle:   derefString(dv.PromptTitle),
+				InputMessage: derefString(dv.Prompt),
+				ErrorTitle:   derefString(dv.ErrorTitle),
+				ErrorMessage: derefString(dv.Error),
+				ErrorStyle:   derefString(dv.ErrorStyle),
+			}
+			if dv.Type == "list" && strings.HasPrefix(dv.Formula1, "\"") {
+				rule.ListItems = strings.Split(strings.Trim(dv.Formula1, "\""), ",")
+			}
+			rules = append(rules, rule)
+		}
+
+		jsonData, err := json.Marshal(rules)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal data validations: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 12: delete_data_validation
+	deleteDataValidationTool := mcp.NewTool("delete_data_validation",
+		mcp.WithDescription("Delete data validation rules from a range, or all rules of a worksheet"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Name of the worksheet (optional when range is a defined name)"),
+		),
+		mcp.WithString("range",
+			mcp.Description("Cells to remove validation from, or a defined name. Example: 'A2:A100'. "+
+				"If omitted, every rule on the worksheet is deleted"),
+		),
+	)
+
+	s.AddTool(deleteDataValidationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		sqref, _ := request.Params.Arguments["range"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		// Resolve a defined name to its sheet and range
+		if sqref != "" {
+			if sheetName, sqref, err = resolveSqref(f, sheetName, sqref); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		} else if sheetName == "" {
+			return nil, errors.New("sheet_name is required unless range is a defined name")
+		}
+
+		if sqref == "" {
+			err = f.DeleteDataValidation(sheetName)
+		} else {
+			err = f.DeleteDataValidation(sheetName, sqref)
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete data validation: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		if sqref == "" {
+			return mcp.NewToolResultText(fmt.Sprintf("Deleted all data validations in sheet '%s'", sheetName)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Deleted data validation from %s in sheet '%s'", sqref, sheetName)), nil
+	})
+
+	// Tool 13: check_data_validation
+	checkDataValidationTool := mcp.NewTool("check_data_validation",
+		mcp.WithDescription("Scan existing cell values against the worksheet's data validation rules and "+
+			"report every cell that violates its rule. Excel only validates typed input, so pasted or "+
+			"programmatically written values can break the rules. Empty cells are not checked and "+
+			"custom formula rules are reported as skipped."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet to check. If omitted, every worksheet is checked"),
+		),
+		mcp.WithNumber("max_violations",
+			mcp.Description("Maximum number of violations to list (default: 500)"),
+		),
+	)
+
+	s.AddTool(checkDataValidationTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		maxViolations := 500
+		if v, ok := request.Params.Arguments["max_violations"].(float64); ok && v > 0 {
+			maxViolations = int(v)
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheets := f.GetSheetList()
+		if sheetName != "" {
+			if index, _ := f.GetSheetIndex(sheetName); index == -1 {
+				return mcp.NewToolResultError(fmt.Sprintf("worksheet '%s' not found", sheetName)), nil
+			}
+			sheets = []string{sheetName}
+		}
+
+		report := struct {
+			CheckedCells   int                   `json:"checked_cells"`
+			ViolationCount int                   `json:"violation_count"`
+			Truncated      bool                  `json:"truncated"`
+			Violations     []validationViolation `json:"violations"`
+			SkippedRules   []skippedValidation   `json:"skipped_rules"`
+		}{
+			Violations:   []validationViolation{},
+			SkippedRules: []skippedValidation{},
+		}
+		for _, sheet := range sheets {
+			if !isWorksheet(f, sheet) {
+				continue
+			}
+			checked, total, violations, skipped, err := findValidationViolations(f, sheet, maxViolations-len(report.Violations))
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to check sheet '%s': %v", sheet, err)), nil
+			}
+			report.CheckedCells += checked
+			report.ViolationCount += total
+			report.Violations = append(report.Violations, violations...)
+			report.SkippedRules = append(report.SkippedRules, skipped...)
+		}
+		report.Truncated = report.ViolationCount > len(report.Violations)
+
+		jsonData, err := json.Marshal(report)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal validation report: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 14: create_defined_name
+	createDefinedNameTool := mcp.NewTool("create_defined_name",
+		mcp.WithDescription("Create a defined name (named range) at workbook or sheet scope. "+
+			"Defined names can be used instead of sheet_name and start_cell in other tools."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Name to define. Example: 'SalesData'"),
+		),
+		mcp.WithString("refers_to",
+			mcp.Required(),
+			mcp.Description("Range, cell or formula the name refers to. Example: 'Sheet1!$A$1:$F$500'"),
+		),
+		mcp.WithString("scope",
+			mcp.Description("Worksheet name for a sheet-scoped name. Defaults to the workbook scope"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("Comment describing the name"),
+		),
+	)
+
+	s.AddTool(createDefinedNameTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok || name == "" {
+			return nil, errors.New("name is required and must be a non-empty string")
+		}
+		refersTo, ok := request.Params.Arguments["refers_to"].(string)
+		if !ok || refersTo == "" {
+			return nil, errors.New("refers_to is required and must be a non-empty string")
+		}
+		scope, _ := request.Params.Arguments["scope"].(string)
+		comment, _ := request.Params.Arguments["comment"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		if scope == "Workbook" {
+			scope = ""
+		}
+		if scope != "" {
+			if index, _ := f.GetSheetIndex(scope); index == -1 {
+				return mcp.NewToolResultError(fmt.Sprintf("worksheet '%s' not found", scope)), nil
+			}
+		}
+		if err := f.SetDefinedName(&excelize.DefinedName{
+			Name:     name,
+			RefersTo: strings.TrimPrefix(refersTo, "="),
+			Comment:  comment,
+			Scope:    scope,
+		}); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to create defined name: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Defined name '%s' created referring to %s", name, refersTo)), nil
+	})
+
+	// Tool 15: list_defined_names
+	listDefinedNamesTool := mcp.NewTool("list_defined_names",
+		mcp.WithDescription("List the defined names (named ranges) of a workbook"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+	)
+
+	s.AddTool(listDefinedNamesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		type definedName struct {
+			Name     string `json:"name"`
+			RefersTo string `json:"refers_to"`
+			Scope    string `json:"scope"`
+			Comment  string `json:"comment,omitempty"`
+		}
+		names := []definedName{}
+		for _, dn := range f.GetDefinedName() {
+			names = append(names, definedName{
+				Name:     dn.Name,
+				RefersTo: dn.RefersTo,
+				Scope:    dn.Scope,
+				Comment:  dn.Comment,
+			})
+		}
+
+		jsonData, err := json.Marshal(names)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal defined names: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 16: update_defined_name
+	updateDefinedNameTool := mcp.NewTool("update_defined_name",
+		mcp.WithDescription("Change the range, comment or name of an existing defined name"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Current defined name"),
+		),
+		mcp.WithString("scope",
+			mcp.Description("Worksheet name of a sheet-scoped name. Defaults to the workbook scope"),
+		),
+		mcp.WithString("new_name",
+			mcp.Description("New name (optional)"),
+		),
+		mcp.WithString("refers_to",
+			mcp.Description("New range, cell or formula (optional). Example: 'Sheet1!$A$1:$F$900'"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("New comment (optional)"),
+		),
+	)
+
+	s.AddTool(updateDefinedNameTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok || name == "" {
+			return nil, errors.New("name is required and must be a non-empty string")
+		}
+		scope, _ := request.Params.Arguments["scope"].(string)
+		if scope == "" {
+			scope = "Workbook"
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		existing := findDefinedName(f, name, scope)
+		if existing == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("defined name '%s' not found in scope '%s'", name, scope)), nil
+		}
+
+		updated := *existing
+		if v, ok := request.Params.Arguments["new_name"].(string); ok && v != "" {
+			updated.Name = v
+		}
+		if v, ok := request.Params.Arguments["refers_to"].(string); ok && v != "" {
+			updated.RefersTo = strings.TrimPrefix(v, "=")
+		}
+		if v, ok := request.Params.Arguments["comment"].(string); ok {
+			updated.Comment = v
+		}
+		if existing.Scope == "Workbook" {
+			existing.Scope, updated.Scope = "", ""
+		}
+
+		if err := f.DeleteDefinedName(existing); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to update defined name: %v", err)), nil
+		}
+		if err := f.SetDefinedName(&updated); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to update defined name: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Defined name '%s' updated to refer to %s", updated.Name, updated.RefersTo)), nil
+	})
+
+	// Tool 17: delete_defined_name
+	deleteDefinedNameTool := mcp.NewTool("delete_defined_name",
+		mcp.WithDescription("Delete a defined name from a workbook"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Defined name to delete"),
+		),
+		mcp.WithString("scope",
+			mcp.Description("Worksheet name of a sheet-scoped name. Defaults to the workbook scope"),
+		),
+	)
+
+	s.AddTool(deleteDefinedNameTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		name, ok := request.Params.Arguments["name"].(string)
+		if !ok || name == "" {
+			return nil, errors.New("name is required and must be a non-empty string")
+		}
+		scope, _ := request.Params.Arguments["scope"].(string)
+		if scope == "Workbook" {
+			scope = ""
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		existing := findDefinedName(f, name, scope)
+		if existing == nil {
+			if scope == "" {
+				scope = "Workbook"
+			}
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete defined name: '%s' not found in scope '%s'", name, scope)), nil
+		}
+		if existing.Scope == "Workbook" {
+			existing.Scope = ""
+		}
+		if err := f.DeleteDefinedName(existing); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete defined name: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Defined name '%s' deleted successfully", existing.Name)), nil
+	})
+
+	// Tool 18: describe_workbook
+	describeWorkbookTool := mcp.NewTool("describe_workbook",
+		mcp.WithDescription("Describe a workbook in detail: document and application properties, "+
+			"defined names, and for each sheet its type, visibility, tab color, default row height, "+
+			"merged cells and table/chart/image/pivot table counts"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+	)
+
+	s.AddTool(describeWorkbookTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		docProps, err := f.GetDocProps()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read document properties: %v", err)), nil
+		}
+		appProps, err := f.GetAppProps()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read application properties: %v", err)), nil
+		}
+		sheets, err := describeSheets(f)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		type definedName struct {
+			Name     string `json:"name"`
+			RefersTo string `json:"refers_to"`
+			Scope    string `json:"scope"`
+		}
+		description := struct {
+			Properties    *documentProperties    `json:"properties"`
+			AppProperties *applicationProperties `json:"app_properties"`
+			Sheets        []sheetDescription     `json:"sheets"`
+			DefinedNames  []definedName          `json:"defined_names"`
+		}{
+			Properties:    newDocumentProperties(docProps),
+			AppProperties: newApplicationProperties(appProps),
+			Sheets:        sheets,
+			DefinedNames:  []definedName{},
+		}
+		for _, dn := range f.GetDefinedName() {
+			description.DefinedNames = append(description.DefinedNames, definedName{
+				Name:     dn.Name,
+				RefersTo: dn.RefersTo,
+				Scope:    dn.Scope,
+			})
+		}
+
+		jsonData, err := json.Marshal(description)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal workbook description: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 19: set_document_properties
+	setDocumentPropertiesTool := mcp.NewTool("set_document_properties",
+		mcp.WithDescription("Set core, application and custom document properties of a workbook. "+
+			"Only the properties provided are changed."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("title", mcp.Description("Document title")),
+		mcp.WithString("subject", mcp.Description("Document subject")),
+		mcp.WithString("creator", mcp.Description("Author of the document")),
+		mcp.WithString("keywords", mcp.Description("Keywords, e.g. 'sales; monthly; EMEA'")),
+		mcp.WithString("description", mcp.Description("Document description or comments")),
+		mcp.WithString("category", mcp.Description("Document category")),
+		mcp.WithString("content_status", mcp.Description("Content status, e.g. 'Draft' or 'Final'")),
+		mcp.WithString("last_modified_by", mcp.Description("User who last modified the document")),
+		mcp.WithString("language", mcp.Description("Document language, e.g. 'en-US'")),
+		mcp.WithString("identifier", mcp.Description("Unique identifier of the document")),
+		mcp.WithString("revision", mcp.Description("Revision number")),
+		mcp.WithString("version", mcp.Description("Version number")),
+		mcp.WithString("created", mcp.Description("Creation time in ISO 8601 UTC, e.g. '2025-06-04T22:00:10Z'")),
+		mcp.WithString("modified", mcp.Description("Modification time in ISO 8601 UTC, e.g. '2025-06-04T22:00:10Z'")),
+		mcp.WithString("company", mcp.Description("Company name (application property)")),
+		mcp.WithString("application", mcp.Description("Producing application name (application property)")),
+		mcp.WithString("app_version", mcp.Description("Producing application version in the form XX.YYYY")),
+		mcp.WithObject("custom_properties",
+			mcp.Description("Custom properties as name/value pairs. Values may be strings, numbers, "+
+				"booleans or ISO 8601 date-times; null deletes the property. "+
+				"Example: {\"Source System\": \"SAP\", \"Run ID\": 4711}"),
+		),
+	)
+
+	s.AddTool(setDocumentPropertiesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		var customProps map[string]interface{}
+		if v, ok := request.Params.Arguments["custom_properties"]; ok && v != nil {
+			if customProps, ok = v.(map[string]interface{}); !ok {
+				return nil, errors.New("custom_properties must be an object")
+			}
+		}
+		stringArg := func(name string) string {
+			value, _ := request.Params.Arguments[name].(string)
+			return value
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		// Core properties are replaced as a whole, so merge with the current values
+		docProps, err := f.GetDocProps()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read document properties: %v", err)), nil
+		}
+		for name, field := range map[string]*string{
+			"title":            &docProps.Title,
+			"subject":          &docProps.Subject,
+			"creator":          &docProps.Creator,
+			"keywords":         &docProps.Keywords,
+			"description":      &docProps.Description,
+			"category":         &docProps.Category,
+			"content_status":   &docProps.ContentStatus,
+			"last_modified_by": &docProps.LastModifiedBy,
+			"language":         &docProps.Language,
+			"identifier":       &docProps.Identifier,
+			"revision":         &docProps.Revision,
+			"version":          &docProps.Version,
+			"created":          &docProps.Created,
+			"modified":         &docProps.Modified,
+		} {
+			if value := stringArg(name); value != "" {
+				*field = value
+			}
+		}
+		if err := f.SetDocProps(docProps); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to set document properties: %v", err)), nil
+		}
+
+		// Application properties are replaced as a whole, so merge with the current values
+		company, application, appVersion := stringArg("company"), stringArg("application"), stringArg("app_version")
+		if company != "" || application != "" || appVersion != "" {
+			appProps, err := f.GetAppProps()
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read application properties: %v", err)), nil
+			}
+			if company != "" {
+				appProps.Company = company
+			}
+			if application != "" {
+				appProps.Application = application
+			}
+			if appVersion != "" {
+				appProps.AppVersion = appVersion
+			}
+			if err := f.SetAppProps(appProps); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to set application properties: %v", err)), nil
+			}
+		}
+
+		for name, value := range customProps {
+			if err := f.SetCustomProps(excelize.CustomProperty{Name: name, Value: customPropertyValue(value)}); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to set custom property '%s': %v", name, err)), nil
+			}
+		}
+
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Document properties of %s updated successfully", filepath)), nil
+	})
+
+	// Tool 20: get_document_properties
+	getDocumentPropertiesTool := mcp.NewTool("get_document_properties",
+		mcp.WithDescription("Read the core, application and custom document properties of a workbook"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+	)
+
+	s.AddTool(getDocumentPropertiesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		docProps, err := f.GetDocProps()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read document properties: %v", err)), nil
+		}
+		appProps, err := f.GetAppProps()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read application properties: %v", err)), nil
+		}
+		customProps, err := f.GetCustomProps()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read custom properties: %v", err)), nil
+		}
+
+		properties := struct {
+			Properties       *documentProperties    `json:"properties"`
+			AppProperties    *applicationProperties `json:"app_properties"`
+			CustomProperties map[string]interface{} `json:"custom_properties"`
+		}{
+			Properties:       newDocumentProperties(docProps),
+			AppProperties:    newApplicationProperties(appProps),
+			CustomProperties: make(map[string]interface{}),
+		}
+		for _, prop := range customProps {
+			properties.CustomProperties[prop.Name] = prop.Value
+		}
+
+		jsonData, err := json.Marshal(properties)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal document properties: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 21: move_worksheet
+	moveWorksheetTool := mcp.NewTool("move_worksheet",
+		mcp.WithDescription("Move a sheet to a new position in the tab order"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Name of the sheet to move"),
+		),
+		mcp.WithNumber("position",
+			mcp.Required(),
+			mcp.Description("Zero-based target position, e.g. 0 to make it the first tab"),
+		),
+	)
+
+	s.AddTool(moveWorksheetTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+		position, ok := request.Params.Arguments["position"].(float64)
+		if !ok {
+			return nil, errors.New("position must be a number")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		if err := moveSheet(f, sheetName, int(position)); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to move worksheet: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Worksheet '%s' moved to position %d. Sheet order: %s",
+			sheetName, int(position), strings.Join(f.GetSheetList(), ", "))), nil
+	})
+
+	// Tool 22: set_worksheet_visibility
+	setWorksheetVisibilityTool := mcp.NewTool("set_worksheet_visibility",
+		mcp.WithDescription("Show or hide a sheet. Very hidden sheets can only be made visible again programmatically, "+
+			"not from the Excel user interface."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Name of the sheet"),
+		),
+		mcp.WithString("visibility",
+			mcp.Required(),
+			mcp.Description("Visibility of the sheet"),
+			mcp.Enum("visible", "hidden", "very_hidden"),
+		),
+	)
+
+	s.AddTool(setWorksheetVisibilityTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+		visibility, ok := request.Params.Arguments["visibility"].(string)
+		if !ok {
+			return nil, errors.New("visibility must be a string")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		if err := setSheetVisibility(f, sheetName, visibility); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to set worksheet visibility: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Worksheet '%s' is now %s", sheetName, strings.ReplaceAll(visibility, "_", " "))), nil
+	})
+
+	// Tool 23: set_tab_color
+	setTabColorTool := mcp.NewTool("set_tab_color",
+		mcp.WithDescription("Set the tab color of a worksheet"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Name of the worksheet"),
+		),
+		mcp.WithString("color",
+			mcp.Required(),
+			mcp.Description("Tab color in hexadecimal RGB format (6-digit, no alpha). "+
+				"Example: 'FF0000' for red, '00B050' for green"),
+		),
+	)
+
+	s.AddTool(setTabColorTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+		color, ok := request.Params.Arguments["color"].(string)
+		if !ok {
+			return nil, errors.New("color must be a string")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		if err := setTabColor(f, sheetName, color); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to set tab color: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Tab color of '%s' set to %s", sheetName, color)), nil
+	})
+
+	// Tool 24: set_active_sheet
+	setActiveSheetTool := mcp.NewTool("set_active_sheet",
+		mcp.WithDescription("Set the sheet that is shown when the workbook is opened"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Name of the sheet to activate"),
+		),
+	)
+
+	s.AddTool(setActiveSheetTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		index, err := f.GetSheetIndex(sheetName)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to set active sheet: %v", err)), nil
+		}
+		if index == -1 {
+			return mcp.NewToolResultError(fmt.Sprintf("worksheet '%s' not found", sheetName)), nil
+		}
+		if f.WorkBook.Sheets.Sheet[index].State != "" {
+			return mcp.NewToolResultError(fmt.Sprintf("worksheet '%s' is hidden; make it visible before activating it", sheetName)), nil
+		}
+		activateSheet(f, index)
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Worksheet '%s' is now the active sheet", sheetName)), nil
+	})
+
+	// Tool 25: copy_worksheet
+	copyWorksheetTool := mcp.NewTool("copy_worksheet",
+		mcp.WithDescription("Copy a worksheet within a workbook or into another workbook. "+
+			"Values, formulas, styles, merged cells, column widths, row heights and data validation are copied."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file containing the worksheet to copy"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Name of the worksheet to copy"),
+		),
+		mcp.WithString("new_sheet_name",
+			mcp.Description("Name of the copy (default: same as the source when copying to another workbook)"),
+		),
+		mcp.WithString("target_filepath",
+			mcp.Description("Path to the workbook to copy into (default: the source workbook). "+
+				"A new workbook is created if the file does not exist."),
+		),
+		mcp.WithString("target_file_password",
+			mcp.Description("Password of target_filepath if it is encrypted, or to encrypt a newly created target with "+
+				"(default: not encrypted)"),
+		),
+		mcp.WithNumber("position",
+			mcp.Description("Zero-based tab position of the copy (default: after the last sheet)"),
+		),
+	)
+
+	s.AddTool(copyWorksheetTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+		newSheetName, _ := request.Params.Arguments["new_sheet_name"].(string)
+		targetPath, _ := request.Params.Arguments["target_filepath"].(string)
+		targetPassword, _ := request.Params.Arguments["target_file_password"].(string)
+		sameWorkbook := targetPath == "" || sameFile(filepath, targetPath)
+		if newSheetName == "" {
+			if sameWorkbook {
+				return nil, errors.New("new_sheet_name is required when copying within the same workbook")
+			}
+			newSheetName = sheetName
+		}
+
+		src, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer src.Close()
+		srcIndex, _ := src.GetSheetIndex(sheetName)
+		if srcIndex == -1 {
+			return mcp.NewToolResultError(fmt.Sprintf("worksheet '%s' not found", sheetName)), nil
+		}
+		if !isWorksheet(src, sheetName) {
+			return mcp.NewToolResultError(fmt.Sprintf("'%s' is not a worksheet; only worksheets can be copied", sheetName)), nil
+		}
+
+		dst, newWorkbook := src, false
+		if sameWorkbook {
+			targetPath = filepath
+		} else if _, statErr := os.Stat(targetPath); os.IsNotExist(statErr) {
+			dst, newWorkbook = excelize.NewFile(), true
+			defer dst.Close()
+		} else {
+			if dst, err = openWorkbook(targetPath, targetPassword); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to open target Excel file: %v", err)), nil
+			}
+			defer dst.Close()
+		}
+		if index, _ := dst.GetSheetIndex(newSheetName); index != -1 && !(newWorkbook && newSheetName == "Sheet1") {
+			return mcp.NewToolResultError(fmt.Sprintf("worksheet '%s' already exists", newSheetName)), nil
+		}
+
+		if sameWorkbook {
+			dstIndex, err := dst.NewSheet(newSheetName)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to create worksheet: %v", err)), nil
+			}
+			if err := dst.CopySheet(srcIndex, dstIndex); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to copy worksheet: %v", err)), nil
+			}
+		} else {
+			if err := copyWorksheetAcross(src, sheetName, dst, newSheetName); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to copy worksheet: %v", err)), nil
+			}
+			// Drop the default sheet of a newly created workbook
+			if newWorkbook && newSheetName != "Sheet1" {
+				if err := dst.DeleteSheet("Sheet1"); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to remove default worksheet: %v", err)), nil
+				}
+			}
+		}
+		if position, ok := request.Params.Arguments["position"].(float64); ok {
+			if err := moveSheet(dst, newSheetName, int(position)); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to position worksheet: %v", err)), nil
+			}
+		}
+
+		var saveOpts []excelize.Options
+		if newWorkbook {
+			saveOpts = append(saveOpts, excelize.Options{Password: targetPassword})
+		}
+		if err := dst.SaveAs(targetPath, saveOpts...); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Worksheet '%s' copied to '%s' in %s", sheetName, newSheetName, targetPath)), nil
+	})
+
+	// Tool 26: merge_workbooks
+	mergeWorkbooksTool := mcp.NewTool("merge_workbooks",
+		mcp.WithDescription("Combine sheets from several workbooks into one new workbook. "+
+			"Sheets are copied with their formatting and renamed on conflict, or optionally stacked "+
+			"into a single sheet when they hold tables with the same columns."),
+		mcp.WithString("output_filepath",
+			mcp.Required(),
+			mcp.Description("Path of the merged workbook to create"),
+		),
+		mcp.WithArray("sources",
+			mcp.Required(),
+			mcp.Description("Workbooks to merge, in order. Each item is an object with 'filepath' (required), "+
+				"'sheets' (optional list of sheet names, default: all worksheets) and 'file_password' (if it is encrypted). "+
+				"Example: [{\"filepath\": \"north.xlsx\"}, {\"filepath\": \"south.xlsx\", \"sheets\": [\"Sales\"]}]"),
+			mcp.Items(map[string]interface{}{"type": "object"}),
+		),
+		mcp.WithString("stack_sheet_name",
+			mcp.Description("Stack all selected sheets into one sheet with this name instead of copying them separately. "+
+				"Every sheet must hold a table with a header row in row 1 and the same column names."),
+		),
+		mcp.WithString("source_column",
+			mcp.Description("Header of the column naming the source file of each stacked row (default: 'Source')"),
+			mcp.DefaultString("Source"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("Replace output_filepath if it already exists (default: false)"),
+		),
+		mcp.WithString("new_password",
+			mcp.Description("Encrypt the merged workbook with this password (default: not encrypted)"),
+		),
+	)
+
+	s.AddTool(mergeWorkbooksTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		outputPath, ok := request.Params.Arguments["output_filepath"].(string)
+		if !ok {
+			return nil, errors.New("output_filepath must be a string")
+		}
+		sourcesInterface, ok := request.Params.Arguments["sources"].([]interface{})
+		if !ok || len(sourcesInterface) == 0 {
+			return nil, errors.New("sources must be a non-empty array")
+		}
+		var sources []mergeSource
+		for i, item := range sourcesInterface {
+			source, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("sources[%d] must be an object", i)
+			}
+			path, _ := source["filepath"].(string)
+			if path == "" {
+				return nil, fmt.Errorf("sources[%d].filepath is required", i)
+			}
+			sheets, err := toStringSlice(source["sheets"])
+			if err != nil {
+				return nil, fmt.Errorf("sources[%d].sheets %v", i, err)
+			}
+			password, _ := source["file_password"].(string)
+			sources = append(sources, mergeSource{Filepath: path, Sheets: sheets, Password: password})
+		}
+		stackSheet, _ := request.Params.Arguments["stack_sheet_name"].(string)
+		sourceColumn, _ := request.Params.Arguments["source_column"].(string)
+		if sourceColumn == "" {
+			sourceColumn = "Source"
+		}
+		overwrite, _ := request.Params.Arguments["overwrite"].(bool)
+		newPassword, _ := request.Params.Arguments["new_password"].(string)
+
+		if _, err := os.Stat(outputPath); err == nil && !overwrite {
+			return mcp.NewToolResultError(fmt.Sprintf("%s already exists; set overwrite to replace it", outputPath)), nil
+		}
+		for _, source := range sources {
+			if sameFile(source.Filepath, outputPath) {
+				return mcp.NewToolResultError("output_filepath must not be one of the sources"), nil
+			}
+		}
+
+		out, merged, err := mergeWorkbooks(sources, stackSheet, sourceColumn)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to merge workbooks: %v", err)), nil
+		}
+		defer out.Close()
+		if err := out.SaveAs(outputPath, excelize.Options{Password: newPassword}); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+
+		var summary strings.Builder
+		fmt.Fprintf(&summary, "Merged %d sheets from %d workbooks into %s:", len(merged), len(sources), outputPath)
+		for _, m := range merged {
+			fmt.Fprintf(&summary, "\n- %s!%s -> %s", m.Source, m.Sheet, m.Target)
+			if stackSheet != "" {
+				fmt.Fprintf(&summary, " (rows: %d)", m.Rows)
+			}
+		}
+		return mcp.NewToolResultText(summary.String()), nil
+	})
+
+	// Tool 27: split_by_key
+	splitByKeyTool := mcp.NewTool("split_by_key",
+		mcp.WithDescription("Split a header-based table into one sheet or one workbook file per distinct value "+
+			"of a key column. Header formatting, column styles and widths are preserved."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet holding the table (optional if range is a defined name)"),
+		),
+		mcp.WithString("range",
+			mcp.Description("Table range including the header row, e.g. 'A1:F500', or a defined name "+
+				"(default: the whole sheet starting at A1)"),
+		),
+		mcp.WithString("key_column",
+			mcp.Required(),
+			mcp.Description("Header of the column whose values decide where each row goes, e.g. 'Region'"),
+		),
+		mcp.WithString("output",
+			mcp.Description("Write one sheet per key ('sheets') or one workbook file per key ('files')"),
+			mcp.Enum("sheets", "files"),
+			mcp.DefaultString("sheets"),
+		),
+		mcp.WithString("output_filepath",
+			mcp.Description("For 'sheets': workbook to add the sheets to (default: the source workbook). "+
+				"A new workbook is created if the file does not exist."),
+		),
+		mcp.WithString("output_directory",
+			mcp.Description("For 'files': directory for the workbooks, named <source>_<key>.xlsx "+
+				"(default: the directory of the source file)"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("For 'files': replace workbooks that already exist (default: false)"),
+		),
+		mcp.WithString("output_file_password",
+			mcp.Description("Password of output_filepath if it is encrypted, or to encrypt new workbooks with "+
+				"(default: file_password if the source workbook is encrypted, otherwise not encrypted)"),
+		),
+	)
+
+	s.AddTool(splitByKeyTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		keyColumn, ok := request.Params.Arguments["key_column"].(string)
+		if !ok {
+			return nil, errors.New("key_column must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		cellRange, _ := request.Params.Arguments["range"].(string)
+		output, _ := request.Params.Arguments["output"].(string)
+		if output == "" {
+			output = "sheets"
+		}
+		if output != "sheets" && output != "files" {
+			return nil, errors.New("output must be 'sheets' or 'files'")
+		}
+		outputPath, _ := request.Params.Arguments["output_filepath"].(string)
+		outputDir, _ := request.Params.Arguments["output_directory"].(string)
+		overwrite, _ := request.Params.Arguments["overwrite"].(bool)
+		outputPassword, _ := request.Params.Arguments["output_file_password"].(string)
+		if outputPassword == "" && isEncryptedFile(filepath) {
+			outputPassword = filePassword(request)
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, cellRange, err = resolveRangeRef(f, sheetName, cellRange)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		table, err := readTable(f, sheetName, cellRange)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read table: %v", err)), nil
+		}
+		parts, err := splitTableByKey(table, keyColumn)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to split table: %v", err)), nil
+		}
+		if len(parts) == 0 {
+			return mcp.NewToolResultError("table has no data rows"), nil
+		}
+		startCol, startRow := 1, 1
+		if cellRange != "" {
+			startCol, startRow, _, _, _ = parseRangeRef(cellRange)
+		}
+		format, err := readTableFormat(f, sheetName, startCol, startRow, len(table.Columns))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read table formatting: %v", err)), nil
+		}
+
+		var summary strings.Builder
+		if output == "files" {
+			paths := splitFilePaths(filepath, outputDir, parts)
+			for _, path := range paths {
+				if _, err := os.Stat(path); err == nil && !overwrite {
+					return mcp.NewToolResultError(fmt.Sprintf("%s already exists; set overwrite to replace it", path)), nil
+				}
+			}
+			if outputDir != "" {
+				if err := os.MkdirAll(outputDir, 0o755); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to create output directory: %v", err)), nil
+				}
+			}
+			fmt.Fprintf(&summary, "Split '%s' by '%s' into %d files:", sheetName, keyColumn, len(parts))
+			for i, part := range parts {
+				out := excelize.NewFile()
+				err := out.SetSheetName("Sheet1", sheetName)
+				if err == nil {
+					err = writeTablePart(out, sheetName, table.Columns, part, format)
+				}
+				if err == nil {
+					err = out.SaveAs(paths[i], excelize.Options{Password: outputPassword})
+				}
+				out.Close()
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to write %s: %v", paths[i], err)), nil
+				}
+				fmt.Fprintf(&summary, "\n- %s -> %s (rows: %d)", part.Key, paths[i], len(part.Rows))
+			}
+			return mcp.NewToolResultText(summary.String()), nil
+		}
+
+		out, newWorkbook := f, false
+		if outputPath == "" || sameFile(filepath, outputPath) {
+			outputPath = filepath
+		} else if _, statErr := os.Stat(outputPath); os.IsNotExist(statErr) {
+			out, newWorkbook = excelize.NewFile(), true
+			defer out.Close()
+			if err := out.SetSheetName("Sheet1", placeholderSheet); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to create workbook: %v", err)), nil
+			}
+		} else {
+			if out, err = openWorkbook(outputPath, outputPassword); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to open output Excel file: %v", err)), nil
+			}
+			defer out.Close()
+		}
+
+		fmt.Fprintf(&summary, "Split '%s' by '%s' into %d sheets in %s:", sheetName, keyColumn, len(parts), outputPath)
+		for _, part := range parts {
+			target := safeSheetName(out, part.Key)
+			if err := writeTablePart(out, target, table.Columns, part, format); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to write sheet '%s': %v", target, err)), nil
+			}
+			fmt.Fprintf(&summary, "\n- %s -> %s (rows: %d)", part.Key, target, len(part.Rows))
+		}
+		if newWorkbook {
+			if err := out.DeleteSheet(placeholderSheet); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to remove default worksheet: %v", err)), nil
+			}
+		}
+		var saveOpts []excelize.Options
+		if newWorkbook {
+			saveOpts = append(saveOpts, excelize.Options{Password: outputPassword})
+		}
+		if err := out.SaveAs(outputPath, saveOpts...); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(summary.String()), nil
+	})
+
+	// Tool 28: list_merged_cells
+	listMergedCellsTool := mcp.NewTool("list_merged_cells",
+		mcp.WithDescription("List the merged cell ranges of a worksheet, or of all worksheets, with the value shown in each"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (default: all worksheets)"),
+		),
+	)
+
+	s.AddTool(listMergedCellsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheets := []string{sheetName}
+		if sheetName == "" {
+			sheets = nil
+			for _, sheet := range f.GetSheetList() {
+				if isWorksheet(f, sheet) {
+					sheets = append(sheets, sheet)
+				}
+			}
+		}
+		areas := []mergedArea{}
+		for _, sheet := range sheets {
+			sheetAreas, err := listMergedAreas(f, sheet)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read merged cells of '%s': %v", sheet, err)), nil
+			}
+			areas = append(areas, sheetAreas...)
+		}
+
+		jsonData, err := json.Marshal(areas)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal merged cells: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 29: unmerge_cells
+	unmergeCellsTool := mcp.NewTool("unmerge_cells",
+		mcp.WithDescription("Unmerge every merged area that overlaps a range, optionally filling the merged value "+
+			"into every former cell"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (optional if range is a defined name)"),
+		),
+		mcp.WithString("range",
+			mcp.Required(),
+			mcp.Description("Range to unmerge, e.g. 'A1:D1' or 'A1:Z100' for all merges in that area, or a defined name"),
+		),
+		mcp.WithBoolean("fill",
+			mcp.Description("Write the value of each merged area into all of its cells after unmerging, "+
+				"e.g. to make grouped labels usable as table data (default: false)"),
+		),
+	)
+
+	s.AddTool(unmergeCellsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		cellRange, ok := request.Params.Arguments["range"].(string)
+		if !ok {
+			return nil, errors.New("range must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		fill, _ := request.Params.Arguments["fill"].(bool)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, cellRange, err = resolveRangeRef(f, sheetName, cellRange)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if _, _, _, _, err := parseRangeRef(cellRange); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		unmerged, err := unmergeAreas(f, sheetName, cellRange, fill)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to unmerge cells: %v", err)), nil
+		}
+		if len(unmerged) == 0 {
+			return mcp.NewToolResultText(fmt.Sprintf("No merged cells found in %s of sheet '%s'", cellRange, sheetName)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Unmerged %s in sheet '%s'", strings.Join(unmerged, ", "), sheetName)), nil
+	})
+
+	// Tool 30: check_merge
+	checkMergeTool := mcp.NewTool("check_merge",
+		mcp.WithDescription("Check what merging a range would discard: non-empty cells other than the top-left cell, "+
+			"whose contents are lost, and existing merged areas that overlap the range and would be removed"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (optional if range is a defined name)"),
+		),
+		mcp.WithString("range",
+			mcp.Required(),
+			mcp.Description("Range to check, e.g. 'A1:D1', or a defined name"),
+		),
+	)
+
+	s.AddTool(checkMergeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		cellRange, ok := request.Params.Arguments["range"].(string)
+		if !ok {
+			return nil, errors.New("range must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, cellRange, err = resolveRangeRef(f, sheetName, cellRange)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		check, err := checkMerge(f, sheetName, cellRange)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to check merge: %v", err)), nil
+		}
+
+		jsonData, err := json.Marshal(check)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal merge check: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 31: insert_image
+	insertImageTool := mcp.NewTool("insert_image",
+		mcp.WithDescription("Insert an image such as a logo or chart into a worksheet from a file path or a base64 payload, "+
+			"anchored at a cell with optional scaling, offsets, positioning mode and alt text"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (optional if cell is a defined name)"),
+		),
+		mcp.WithString("cell",
+			mcp.Required(),
+			mcp.Description("Cell the top-left corner of the image is anchored to, e.g. 'B2', or a defined name"),
+		),
+		mcp.WithString("image_path",
+			mcp.Description("Path to the image file (png, jpg, jpeg, gif, svg, emf, emz, wmf or wmz); "+
+				"either image_path or image_base64 is required"),
+		),
+		mcp.WithString("image_base64",
+			mcp.Description("Base64-encoded image data, optionally as a data URI such as 'data:image/png;base64,...'"),
+		),
+		mcp.WithString("image_format",
+			mcp.Description("Format of image_base64, e.g. 'png' or 'jpeg' (optional if image_base64 is a data URI)"),
+		),
+		mcp.WithNumber("scale_x",
+			mcp.Description("Horizontal scale factor, e.g. 0.5 for half width (default: 1)"),
+		),
+		mcp.WithNumber("scale_y",
+			mcp.Description("Vertical scale factor (default: 1)"),
+		),
+		mcp.WithNumber("offset_x",
+			mcp.Description("Horizontal offset from the anchor cell in pixels (default: 0)"),
+		),
+		mcp.WithNumber("offset_y",
+			mcp.Description("Vertical offset from the anchor cell in pixels (default: 0)"),
+		),
+		mcp.WithString("positioning",
+			mcp.Description("How the image behaves when cells are resized: 'twoCell' moves and sizes with cells, "+
+				"'oneCell' moves but does not size with cells, 'absolute' neither moves nor sizes (default: twoCell)"),
+			mcp.Enum("twoCell", "oneCell", "absolute"),
+		),
+		mcp.WithString("alt_text",
+			mcp.Description("Alternative text describing the image for accessibility"),
+		),
+		mcp.WithBoolean("lock_aspect_ratio",
+			mcp.Description("Keep the aspect ratio when the image is resized in Excel (default: false)"),
+		),
+		mcp.WithBoolean("autofit",
+			mcp.Description("Scale the image to fit the anchor cell or merged area, keeping its aspect ratio (default: false)"),
+		),
+	)
+
+	s.AddTool(insertImageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		cell, ok := request.Params.Arguments["cell"].(string)
+		if !ok {
+			return nil, errors.New("cell must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		imagePath, _ := request.Params.Arguments["image_path"].(string)
+		imageBase64, _ := request.Params.Arguments["image_base64"].(string)
+		imageFormat, _ := request.Params.Arguments["image_format"].(string)
+		if (imagePath == "") == (imageBase64 == "") {
+			return nil, errors.New("exactly one of image_path or image_base64 is required")
+		}
+
+		options := &excelize.GraphicOptions{ScaleX: 1, ScaleY: 1}
+		options.AltText, _ = request.Params.Arguments["alt_text"].(string)
+		options.Positioning, _ = request.Params.Arguments["positioning"].(string)
+		if options.Positioning == "twoCell" {
+			options.Positioning = ""
+		}
+		options.LockAspectRatio, _ = request.Params.Arguments["lock_aspect_ratio"].(bool)
+		options.AutoFit, _ = request.Params.Arguments["autofit"].(bool)
+		if v, ok := request.Params.Arguments["scale_x"].(float64); ok {
+			options.ScaleX = v
+		}
+		if v, ok := request.Params.Arguments["scale_y"].(float64); ok {
+			options.ScaleY = v
+		}
+		if options.ScaleX <= 0 || options.ScaleY <= 0 {
+			return nil, errors.New("scale_x and scale_y must be greater than 0")
+		}
+		if v, ok := request.Params.Arguments["offset_x"].(float64); ok {
+			options.OffsetX = int(v)
+		}
+		if v, ok := request.Params.Arguments["offset_y"].(float64); ok {
+			options.OffsetY = int(v)
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, cell, _, err = resolveCellRef(f, sheetName, cell)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		var data []byte
+		var extension string
+		if imagePath != "" {
+			data, extension, err = readImageFile(imagePath)
+		} else {
+			data, extension, err = decodeImageData(imageBase64, imageFormat)
+		}
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err := checkMediaName(f, data); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to insert image: %v", err)), nil
+		}
+		err = f.AddPictureFromBytes(sheetName, cell, &excelize.Picture{
+			Extension:  extension,
+			File:       data,
+			Format:     options,
+			InsertType: excelize.PictureInsertTypePlaceOverCells,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to insert image: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Inserted image at %s in sheet '%s'", cell, sheetName)), nil
+	})
+
+	// Tool 32: list_images
+	listImagesTool := mcp.NewTool("list_images",
+		mcp.WithDescription("List the images in a worksheet, or in all worksheets, with their anchor cell, format, "+
+			"size and alt text"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (default: all worksheets)"),
+		),
+	)
+
+	s.AddTool(listImagesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheets := []string{sheetName}
+		if sheetName == "" {
+			sheets = nil
+			for _, sheet := range f.GetSheetList() {
+				if isWorksheet(f, sheet) {
+					sheets = append(sheets, sheet)
+				}
+			}
+		}
+		pictures, err := collectPictures(f, sheets)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		images := make([]imageInfo, 0, len(pictures))
+		for _, pic := range pictures {
+			images = append(images, newImageInfo(pic))
+		}
+
+		jsonData, err := json.Marshal(images)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal images: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 33: delete_image
+	deleteImageTool := mcp.NewTool("delete_image",
+		mcp.WithDescription("Delete the images anchored at a cell of a worksheet"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (optional if cell is a defined name)"),
+		),
+		mcp.WithString("cell",
+			mcp.Required(),
+			mcp.Description("Anchor cell of the images to delete, as reported by list_images, or a defined name"),
+		),
+	)
+
+	s.AddTool(deleteImageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		cell, ok := request.Params.Arguments["cell"].(string)
+		if !ok {
+			return nil, errors.New("cell must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, cell, _, err = resolveCellRef(f, sheetName, cell)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		pictures, err := f.GetPictures(sheetName, cell)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read images: %v", err)), nil
+		}
+		// Images placed in a cell are part of its value and are removed by clearing the cell
+		placed := 0
+		for _, pic := range pictures {
+			if pic.InsertType == excelize.PictureInsertTypePlaceOverCells {
+				placed++
+			}
+		}
+		if placed == 0 {
+			if len(pictures) > 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("the image at %s in sheet '%s' is placed in the cell; "+
+					"clear the cell to remove it", cell, sheetName)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("No images found at %s in sheet '%s'", cell, sheetName)), nil
+		}
+		if err := f.DeletePicture(sheetName, cell); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete images: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Deleted %d image(s) at %s in sheet '%s'", placed, cell, sheetName)), nil
+	})
+
+	// Tool 34: extract_images
+	extractImagesTool := mcp.NewTool("extract_images",
+		mcp.WithDescription("Extract the images of a worksheet, or of all worksheets, such as embedded screenshots "+
+			"and signatures. Images are returned as image content or, with output_directory, written to files."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (default: all worksheets)"),
+		),
+		mcp.WithString("cell",
+			mcp.Description("Only extract the images anchored at this cell (requires sheet_name)"),
+		),
+		mcp.WithString("output_directory",
+			mcp.Description("Directory to write the images to, named <sheet>_<cell>.<format>, "+
+				"instead of returning them as image content"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("With output_directory: replace files that already exist (default: false)"),
+		),
+	)
+
+	s.AddTool(extractImagesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		cell, _ := request.Params.Arguments["cell"].(string)
+		if cell != "" && sheetName == "" {
+			return nil, errors.New("sheet_name is required with cell")
+		}
+		outputDir, _ := request.Params.Arguments["output_directory"].(string)
+		overwrite, _ := request.Params.Arguments["overwrite"].(bool)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		var pictures []sheetPicture
+		if cell != "" {
+			cellPictures, err := f.GetPictures(sheetName, cell)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read images: %v", err)), nil
+			}
+			for _, pic := range cellPictures {
+				pictures = append(pictures, sheetPicture{Sheet: sheetName, Cell: cell, Picture: pic})
+			}
+		} else {
+			sheets := []string{sheetName}
+			if sheetName == "" {
+				sheets = nil
+				for _, sheet := range f.GetSheetList() {
+					if isWorksheet(f, sheet) {
+						sheets = append(sheets, sheet)
+					}
+				}
+			}
+			if pictures, err = collectPictures(f, sheets); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+		if len(pictures) == 0 {
+			return mcp.NewToolResultText("No images found"), nil
+		}
+
+		images := make([]imageInfo, len(pictures))
+		for i, pic := range pictures {
+			images[i] = newImageInfo(pic)
+		}
+		if outputDir != "" {
+			paths := imageFilePaths(pictures, outputDir)
+			for _, path := range paths {
+				if _, err := os.Stat(path); err == nil && !overwrite {
+					return mcp.NewToolResultError(fmt.Sprintf("%s already exists; set overwrite to replace it", path)), nil
+				}
+			}
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to create output directory: %v", err)), nil
+			}
+			for i, pic := range pictures {
+				if err := os.WriteFile(paths[i], pic.Picture.File, 0o644); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to write %s: %v", paths[i], err)), nil
+				}
+				images[i].Path = paths[i]
+			}
+		}
+
+		jsonData, err := json.Marshal(images)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal images: %v", err)), nil
+		}
+		result := &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(string(jsonData))}}
+		if outputDir != "" {
+			return result, nil
+		}
+		for i, pic := range pictures {
+			mimeType, ok := imageMIMETypes[images[i].Format]
+			if !ok {
+				result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf(
+					"The %s image at %s in sheet '%s' cannot be returned as image content; use output_directory to save it",
+					images[i].Format, pic.Cell, pic.Sheet)))
+				continue
+			}
+			result.Content = append(result.Content,
+				mcp.NewImageContent(base64.StdEncoding.EncodeToString(pic.Picture.File), mimeType))
+		}
+		return result, nil
+	})
+
+	// Tool 35: add_comment
+	addCommentTool := mcp.NewTool("add_comment",
+		mcp.WithDescription("Add a comment (note) to a cell with an author and plain or rich text, "+
+			"replacing the cell's existing comment or replying to it"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (optional if cell is a defined name)"),
+		),
+		mcp.WithString("cell",
+			mcp.Required(),
+			mcp.Description("Cell to comment on, e.g. 'B4', or a defined name"),
+		),
+		mcp.WithString("text",
+			mcp.Description("Comment text; either text or rich_text is required"),
+		),
+		mcp.WithArray("rich_text",
+			mcp.Description("Comment text as formatted runs. Each item is an object with 'text' (required) and optional "+
+				"'bold', 'italic', 'underline' ('single' or 'double'), 'font_size', 'font_family' and 'font_color' (hex RGB). "+
+				"Example: [{\"text\": \"Check: \", \"bold\": true}, {\"text\": \"totals differ from Q2\"}]"),
+			mcp.Items(map[string]interface{}{"type": "object"}),
+		),
+		mcp.WithString("author",
+			mcp.Description("Author of the comment (default: 'Author')"),
+		),
+		mcp.WithBoolean("reply",
+			mcp.Description("Append to the cell's existing comment as a reply headed by the author's name "+
+				"instead of replacing it (default: false)"),
+		),
+		mcp.WithNumber("width",
+			mcp.Description("Width of the comment box in pixels (default: 160)"),
+		),
+		mcp.WithNumber("height",
+			mcp.Description("Height of the comment box in pixels (default: 60)"),
+		),
+	)
+
+	s.AddTool(addCommentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		cell, ok := request.Params.Arguments["cell"].(string)
+		if !ok {
+			return nil, errors.New("cell must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		text, _ := request.Params.Arguments["text"].(string)
+		author, _ := request.Params.Arguments["author"].(string)
+		reply, _ := request.Params.Arguments["reply"].(bool)
+
+		var runs []richTextRun
+		if richText, ok := request.Params.Arguments["rich_text"]; ok && richText != nil {
+			richTextJSON, err := json.Marshal(richText)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rich_text: %w", err)
+			}
+			if err := json.Unmarshal(richTextJSON, &runs); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid rich_text: %v", err)), nil
+			}
+		}
+		if (text == "") == (len(runs) == 0) {
+			return nil, errors.New("exactly one of text or rich_text is required")
+		}
+		comment := excelize.Comment{Author: author, Text: text}
+		for _, run := range runs {
+			comment.Paragraph = append(comment.Paragraph, run.toExcelize())
+		}
+		if width, ok := request.Params.Arguments["width"].(float64); ok && width > 0 {
+			comment.Width = uint(width)
+		}
+		if height, ok := request.Params.Arguments["height"].(float64); ok && height > 0 {
+			comment.Height = uint(height)
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, comment.Cell, _, err = resolveCellRef(f, sheetName, cell)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if comment.Cell, err = normalizeCell(comment.Cell); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid cell: %v", err)), nil
+		}
+		action := "Added"
+		if reply {
+			existing, err := findComment(f, sheetName, comment.Cell)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read comments: %v", err)), nil
+			}
+			if existing != nil {
+				if comment.Paragraph == nil {
+					comment.Paragraph = []excelize.RichTextRun{{Text: comment.Text}}
+				}
+				if author == "" {
+					author = "Author"
+				}
+				comment.Paragraph = replyParagraph(existing, author, comment.Paragraph)
+				comment.Author, comment.Text = existing.Author, ""
+				action = "Replied to"
+			}
+		}
+		if err := setComment(f, sheetName, comment); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to add comment: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("%s the comment at %s in sheet '%s'", action, comment.Cell, sheetName)), nil
+	})
+
+	// Tool 36: list_comments
+	listCommentsTool := mcp.NewTool("list_comments",
+		mcp.WithDescription("List the comments of a worksheet, or of all worksheets, with their cell, author and text"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (default: all worksheets)"),
+		),
+	)
+
+	s.AddTool(listCommentsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheets := []string{sheetName}
+		if sheetName == "" {
+			sheets = nil
+			for _, sheet := range f.GetSheetList() {
+				if isWorksheet(f, sheet) {
+					sheets = append(sheets, sheet)
+				}
+			}
+		}
+		comments := []commentInfo{}
+		for _, sheet := range sheets {
+			sheetComments, err := f.GetComments(sheet)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read comments of '%s': %v", sheet, err)), nil
+			}
+			for _, c := range sheetComments {
+				comments = append(comments, newCommentInfo(sheet, c))
+			}
+		}
+
+		jsonData, err := json.Marshal(comments)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal comments: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 37: delete_comment
+	deleteCommentTool := mcp.NewTool("delete_comment",
+		mcp.WithDescription("Delete the comment of a cell"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (optional if cell is a defined name)"),
+		),
+		mcp.WithString("cell",
+			mcp.Required(),
+			mcp.Description("Cell whose comment to delete, e.g. 'B4', or a defined name"),
+		),
+	)
+
+	s.AddTool(deleteCommentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		cell, ok := request.Params.Arguments["cell"].(string)
+		if !ok {
+			return nil, errors.New("cell must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, cell, _, err = resolveCellRef(f, sheetName, cell)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if cell, err = normalizeCell(cell); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid cell: %v", err)), nil
+		}
+		existing, err := findComment(f, sheetName, cell)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read comments: %v", err)), nil
+		}
+		if existing == nil {
+			return mcp.NewToolResultText(fmt.Sprintf("No comment found at %s in sheet '%s'", cell, sheetName)), nil
+		}
+		if err := f.DeleteComment(sheetName, cell); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to delete comment: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Deleted the comment at %s in sheet '%s'", cell, sheetName)), nil
+	})
+
+	// Tool 38: set_hyperlink
+	setHyperlinkTool := mcp.NewTool("set_hyperlink",
+		mcp.WithDescription("Make a cell a clickable link to an external URL or to a location in the workbook, "+
+			"with optional display text and tooltip, or remove its link"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (optional if cell is a defined name)"),
+		),
+		mcp.WithString("cell",
+			mcp.Required(),
+			mcp.Description("Cell to link, e.g. 'A2', or a defined name"),
+		),
+		mcp.WithString("url",
+			mcp.Description("External target, e.g. 'https://example.com/report' or 'mailto:team@example.com'"),
+		),
+		mcp.WithString("location",
+			mcp.Description("Target in the workbook, e.g. 'Sheet2!A1', \"'Q3 Sales'!B4\" or a defined name. "+
+				"Exactly one of url or location is required unless remove is set"),
+		),
+		mcp.WithString("display_text",
+			mcp.Description("Text shown in the cell (default: the current cell value, or the target if the cell is empty)"),
+		),
+		mcp.WithString("tooltip",
+			mcp.Description("Text shown when hovering over the link"),
+		),
+		mcp.WithBoolean("style",
+			mcp.Description("Format the cell as a link with a blue, underlined font (default: true)"),
+		),
+		mcp.WithBoolean("remove",
+			mcp.Description("Remove the cell's hyperlink instead, keeping its value (default: false)"),
+		),
+	)
+
+	s.AddTool(setHyperlinkTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		cell, ok := request.Params.Arguments["cell"].(string)
+		if !ok {
+			return nil, errors.New("cell must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		url, _ := request.Params.Arguments["url"].(string)
+		location, _ := request.Params.Arguments["location"].(string)
+		location = strings.TrimPrefix(location, "#")
+		displayText, _ := request.Params.Arguments["display_text"].(string)
+		tooltip, _ := request.Params.Arguments["tooltip"].(string)
+		remove, _ := request.Params.Arguments["remove"].(bool)
+		applyStyle := true
+		if v, ok := request.Params.Arguments["style"].(bool); ok {
+			applyStyle = v
+		}
+		if !remove && (url == "") == (location == "") {
+			return nil, errors.New("exactly one of url or location is required")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheetName, cell, _, err = resolveCellRef(f, sheetName, cell)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		hasLink, _, err := f.GetCellHyperLink(sheetName, cell)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read hyperlink: %v", err)), nil
+		}
+		if remove {
+			if !hasLink {
+				return mcp.NewToolResultText(fmt.Sprintf("No hyperlink found at %s in sheet '%s'", cell, sheetName)), nil
+			}
+			if err := f.SetCellHyperLink(sheetName, cell, "", "None"); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to remove hyperlink: %v", err)), nil
+			}
+			if err := f.Save(); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+			}
+			return mcp.NewToolResultText(fmt.Sprintf("Removed the hyperlink at %s in sheet '%s'", cell, sheetName)), nil
+		}
+
+		target, linkType := url, "External"
+		if location != "" {
+			if err := checkLinkLocation(f, sheetName, location); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			target, linkType = location, "Location"
+		}
+		// Replace an existing link entirely so that a former external target leaves no relationship behind
+		if hasLink {
+			if err := f.SetCellHyperLink(sheetName, cell, "", "None"); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to replace hyperlink: %v", err)), nil
+			}
+		}
+		if displayText == "" {
+			value, err := f.GetCellValue(sheetName, cell)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read cell: %v", err)), nil
+			}
+			if value == "" {
+				displayText = target
+			}
+		}
+		var opts excelize.HyperlinkOpts
+		if displayText != "" {
+			opts.Display = &displayText
+			if err := f.SetCellStr(sheetName, cell, displayText); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to set display text: %v", err)), nil
+			}
+		}
+		if tooltip != "" {
+			opts.Tooltip = &tooltip
+		}
+		if err := f.SetCellHyperLink(sheetName, cell, target, linkType, opts); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to set hyperlink: %v", err)), nil
+		}
+		if applyStyle {
+			if err := styleAsHyperlink(f, sheetName, cell); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to style hyperlink: %v", err)), nil
+			}
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Linked %s in sheet '%s' to %s", cell, sheetName, target)), nil
+	})
+
+	// Tool 39: list_hyperlinks
+	listHyperlinksTool := mcp.NewTool("list_hyperlinks",
+		mcp.WithDescription("List the hyperlinks of a worksheet, or of all worksheets, with their cell, type "+
+			"('url' or 'location'), target, display text and tooltip"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet name (default: all worksheets)"),
+		),
+	)
+
+	s.AddTool(listHyperlinksTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		sheets := []string{sheetName}
+		if sheetName == "" {
+			sheets = nil
+			for _, sheet := range f.GetSheetList() {
+				if isWorksheet(f, sheet) {
+					sheets = append(sheets, sheet)
+				}
+			}
+		}
+		links := []hyperlinkInfo{}
+		for _, sheet := range sheets {
+			sheetLinks, err := listHyperlinks(f, sheet)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to read hyperlinks of '%s': %v", sheet, err)), nil
+			}
+			links = append(links, sheetLinks...)
+		}
+
+		jsonData, err := json.Marshal(links)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal hyperlinks: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(jsonData)), nil
+	})
+
+	// Tool 40: protect_sheet
+	protectSheetTool := mcp.NewTool("protect_sheet",
+		mcp.WithDescription("Protect a worksheet so that locked cells cannot be edited, optionally with a password "+
+			"and with selected actions still allowed. Cells are locked by default; unlock input cells with "+
+			"format_range's protection_lock before protecting."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Worksheet name"),
+		),
+		mcp.WithString("password",
+			mcp.Description("Password required to unprotect the sheet (default: none)"),
+		),
+		mcp.WithArray("allow",
+			mcp.Description("Actions users may still perform on the protected sheet. "+
+				"Example: [\"format_cells\", \"insert_rows\", \"sort\", \"autofilter\"]"),
+			mcp.Items(map[string]interface{}{"type": "string", "enum": sheetPermissionNames()}),
+		),
+		mcp.WithBoolean("select_locked_cells",
+			mcp.Description("Allow selecting locked cells (default: true)"),
+		),
+		mcp.WithBoolean("select_unlocked_cells",
+			mcp.Description("Allow selecting unlocked cells (default: true)"),
+		),
+	)
+
+	s.AddTool(protectSheetTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+		opts := &excelize.SheetProtectionOptions{SelectLockedCells: true, SelectUnlockedCells: true}
+		opts.Password, _ = request.Params.Arguments["password"].(string)
+		if opts.Password != "" {
+			opts.AlgorithmName = protectionAlgorithm
+		}
+		allow, err := toStringSlice(request.Params.Arguments["allow"])
+		if err != nil {
+			return nil, fmt.Errorf("allow %v", err)
+		}
+		permissions := sheetPermissions(opts)
+		for _, action := range allow {
+			permission, ok := permissions[action]
+			if !ok {
+				return nil, fmt.Errorf("unknown action %q in allow; use one of %s", action, strings.Join(sheetPermissionNames(), ", "))
+			}
+			*permission = true
+		}
+		if v, ok := request.Params.Arguments["select_locked_cells"].(bool); ok {
+			opts.SelectLockedCells = v
+		}
+		if v, ok := request.Params.Arguments["select_unlocked_cells"].(bool); ok {
+			opts.SelectUnlockedCells = v
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		if err := f.ProtectSheet(sheetName, opts); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to protect sheet: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		message := fmt.Sprintf("Protected sheet '%s'", sheetName)
+		if opts.Password != "" {
+			message += " with a password"
+		}
+		if len(allow) > 0 {
+			message += "; allowed: " + strings.Join(allow, ", ")
+		}
+		return mcp.NewToolResultText(message), nil
+	})
+
+	// Tool 41: unprotect_sheet
+	unprotectSheetTool := mcp.NewTool("unprotect_sheet",
+		mcp.WithDescription("Remove the protection of a worksheet"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Required(),
+			mcp.Description("Worksheet name"),
+		),
+		mcp.WithString("password",
+			mcp.Description("Password the sheet was protected with (required if it has one)"),
+		),
+	)
+
+	s.AddTool(unprotectSheetTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, ok := request.Params.Arguments["sheet_name"].(string)
+		if !ok {
+			return nil, errors.New("sheet_name must be a string")
+		}
+		password, _ := request.Params.Arguments["password"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		state, err := readSheetProtection(f, sheetName)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !state.Protected {
+			return mcp.NewToolResultText(fmt.Sprintf("Sheet '%s' is not protected", sheetName)), nil
+		}
+		// excelize removes the protection without a password, so require it whenever one is set
+		if state.HasPassword && password == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("sheet '%s' is protected with a password; provide password", sheetName)), nil
+		}
+		var passwords []string
+		if state.HasPassword {
+			passwords = append(passwords, password)
+		}
+		if err := f.UnprotectSheet(sheetName, passwords...); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to unprotect sheet: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Removed the protection of sheet '%s'", sheetName)), nil
+	})
+
+	// Tool 42: protect_workbook
+	protectWorkbookTool := mcp.NewTool("protect_workbook",
+		mcp.WithDescription("Protect the workbook structure so that sheets cannot be added, deleted, renamed, moved, "+
+			"hidden or unhidden, optionally with a password"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("password",
+			mcp.Description("Password required to unprotect the workbook (default: none)"),
+		),
+		mcp.WithBoolean("lock_structure",
+			mcp.Description("Lock the sheet structure (default: true)"),
+		),
+		mcp.WithBoolean("lock_windows",
+			mcp.Description("Lock the size and position of the workbook windows (default: false)"),
+		),
+	)
+
+	s.AddTool(protectWorkbookTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		opts := &excelize.WorkbookProtectionOptions{LockStructure: true}
+		opts.Password, _ = request.Params.Arguments["password"].(string)
+		if opts.Password != "" {
+			opts.AlgorithmName = protectionAlgorithm
+		}
+		if v, ok := request.Params.Arguments["lock_structure"].(bool); ok {
+			opts.LockStructure = v
+		}
+		opts.LockWindows, _ = request.Params.Arguments["lock_windows"].(bool)
+		if !opts.LockStructure && !opts.LockWindows {
+			return nil, errors.New("at least one of lock_structure or lock_windows must be true")
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		if err := f.ProtectWorkbook(opts); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to protect workbook: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		message := "Protected the workbook"
+		if opts.Password != "" {
+			message += " with a password"
+		}
+		return mcp.NewToolResultText(message), nil
+	})
+
+	// Tool 43: unprotect_workbook
+	unprotectWorkbookTool := mcp.NewTool("unprotect_workbook",
+		mcp.WithDescription("Remove the protection of the workbook structure and windows"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("password",
+			mcp.Description("Password the workbook was protected with (required if it has one)"),
+		),
+	)
+
+	s.AddTool(unprotectWorkbookTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		password, _ := request.Params.Arguments["password"].(string)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		state, err := readWorkbookProtection(f)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !state.Protected {
+			return mcp.NewToolResultText("The workbook is not protected"), nil
+		}
+		if state.HasPassword && password == "" {
+			return mcp.NewToolResultError("the workbook is protected with a password; provide password"), nil
+		}
+		var passwords []string
+		if state.HasPassword {
+			passwords = append(passwords, password)
+		}
+		if err := f.UnprotectWorkbook(passwords...); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to unprotect workbook: %v", err)), nil
+		}
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		return mcp.NewToolResultText("Removed the protection of the workbook"), nil
+	})
+
+	// Tool 44: encrypt_workbook
+	encryptWorkbookTool := mcp.NewTool("encrypt_workbook",
+		mcp.WithDescription("Encrypt a workbook with a password so that it cannot be opened without it, "+
+			"change the password of an encrypted workbook, or remove its encryption"),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("new_password",
+			mcp.Description("Password to encrypt the workbook with; required unless decrypt is set"),
+		),
+		mcp.WithBoolean("decrypt",
+			mcp.Description("Remove the encryption instead, saving the workbook unencrypted (default: false)"),
+		),
+		mcp.WithString("output_filepath",
+			mcp.Description("Save the result to this path instead of replacing the workbook"),
+		),
+	)
+
+	s.AddTool(encryptWorkbookTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		newPassword, _ := request.Params.Arguments["new_password"].(string)
+		decrypt, _ := request.Params.Arguments["decrypt"].(bool)
+		if decrypt == (newPassword != "") {
+			return nil, errors.New("exactly one of new_password or decrypt is required")
+		}
+		outputPath, _ := request.Params.Arguments["output_filepath"].(string)
+		if outputPath == "" {
+			outputPath = filepath
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		if err := f.SaveAs(outputPath, excelize.Options{Password: newPassword}); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+		if decrypt {
+			return mcp.NewToolResultText(fmt.Sprintf("Saved %s without encryption", outputPath)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Encrypted %s with the new password", outputPath)), nil
+	})
+
+	// Tool 45: bulk_write_data
+	bulkWriteDataTool := mcp.NewTool("bulk_write_data",
+		mcp.WithDescription("Write a large number of rows to a new or empty worksheet using a streaming writer, "+
+			"which is much faster and uses less memory than write_data_to_excel. "+
+			"The workbook is created if it does not exist, and so is the worksheet."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Name of the worksheet to write to; an existing worksheet must be empty "+
+				"(optional when start_cell is a defined name)"),
+		),
+		mcp.WithArray("data",
+			mcp.Required(),
+			mcp.Description("List of lists containing data to write (sublists are rows)"),
+		),
+		mcp.WithString("start_cell",
+			mcp.Description("Cell or defined name to start writing to (default: A1)"),
+		),
+		mcp.WithNumber("header_rows",
+			mcp.Description("Number of leading rows of data that are headers and get header_style (default: 0)"),
+		),
+		mcp.WithObject("header_style",
+			mcp.Description("Formatting of the header rows. Fields (all optional): 'bold' (default: true), 'italic', "+
+				"'font_size', 'font_color', 'bg_color', 'horizontal_align', 'wrap_text', 'border_type', 'border_color', "+
+				"as in format_range. Example: {\"bg_color\": \"DDEBF7\", \"border_type\": \"thin\"}"),
+		),
+		mcp.WithArray("column_widths",
+			mcp.Description("Widths of the columns written, starting at the column of start_cell; 0 keeps the default width. "+
+				"Example: [12, 30, 15]"),
+			mcp.Items(map[string]interface{}{"type": "number"}),
+		),
+		mcp.WithArray("merge_cells",
+			mcp.Description("Ranges to merge, such as header cells spanning several columns. Example: ['A1:D1']"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+
+	s.AddTool(bulkWriteDataTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		dataInterface, ok := request.Params.Arguments["data"].([]interface{})
+		if !ok {
+			return nil, errors.New("data must be an array")
+		}
+		data := make([][]interface{}, len(dataInterface))
+		for i, rowInterface := range dataInterface {
+			rowSlice, ok := rowInterface.([]interface{})
+			if !ok {
+				return nil, errors.New("each data element must be an array")
+			}
+			data[i] = rowSlice
+		}
+		startCell, _ := request.Params.Arguments["start_cell"].(string)
+		if startCell == "" {
+			startCell = "A1"
+		}
+
+		var layout streamLayout
+		if headerRows, ok := request.Params.Arguments["header_rows"].(float64); ok {
+			if headerRows < 0 {
+				return nil, errors.New("header_rows must not be negative")
+			}
+			layout.HeaderRows = int(headerRows)
+		}
+		var header headerStyle
+		if styleArg, ok := request.Params.Arguments["header_style"]; ok && styleArg != nil {
+			styleJSON, err := json.Marshal(styleArg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid header_style: %w", err)
+			}
+			if err := json.Unmarshal(styleJSON, &header); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid header_style: %v", err)), nil
+			}
+		}
+		var err error
+		if layout.HeaderStyle, err = header.toExcelize(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid header_style: %v", err)), nil
+		}
+		if widths, ok := request.Params.Arguments["column_widths"].([]interface{}); ok {
+			for _, w := range widths {
+				width, ok := w.(float64)
+				if !ok {
+					return nil, errors.New("column_widths must be an array of numbers")
+				}
+				layout.ColumnWidths = append(layout.ColumnWidths, width)
+			}
+		}
+		if merges, ok := request.Params.Arguments["merge_cells"].([]interface{}); ok {
+			for _, m := range merges {
+				ref, ok := m.(string)
+				if !ok {
+					return nil, errors.New("merge_cells must be an array of strings")
+				}
+				layout.MergeCells = append(layout.MergeCells, ref)
+			}
+		}
+
+		var f *excelize.File
+		newWorkbook := false
+		if _, err := os.Stat(filepath); os.IsNotExist(err) {
+			newWorkbook = true
+			f = excelize.NewFile()
+			if sheetName != "" {
+				if err := f.SetSheetName(f.GetSheetName(0), sheetName); err != nil {
+					f.Close()
+					return mcp.NewToolResultError(fmt.Sprintf("invalid sheet name: %v", err)), nil
+				}
+			}
+		} else if f, err = openWorkbook(filepath, filePassword(request)); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		// Resolve a defined name to its sheet and top-left cell
+		sheetName, startCell, _, err = resolveCellRef(f, sheetName, startCell)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col, row, _ := excelize.CellNameToCoordinates(startCell)
+
+		if index, _ := f.GetSheetIndex(sheetName); index == -1 {
+			if _, err := f.NewSheet(sheetName); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to create sheet: %v", err)), nil
+			}
+		} else if !isWorksheet(f, sheetName) {
+			return mcp.NewToolResultError(fmt.Sprintf("'%s' is a chart sheet", sheetName)), nil
+		} else if empty, err := isSheetEmpty(f, sheetName); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read sheet: %v", err)), nil
+		} else if !empty {
+			return mcp.NewToolResultError(fmt.Sprintf("sheet '%s' already has cells, such as values, formulas or styled cells; "+
+				"bulk_write_data only writes to new or empty sheets, use write_data_to_excel to write to it", sheetName)), nil
+		}
+
+		written, err := streamRows(f, sheetName, col, row, data, layout)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to write data: %v", err)), nil
+		}
+
+		if newWorkbook {
+			err = f.SaveAs(filepath, excelize.Options{Password: filePassword(request)})
+		} else {
+			err = f.Save()
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+
+		if written == "" {
+			return mcp.NewToolResultText(fmt.Sprintf("Wrote no rows to '%s'", sheetName)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Successfully wrote %d rows to '%s' (%s)", len(data), sheetName, written)), nil
+	})
+
+	// Tool 46: upsert_rows
+	upsertRowsTool := mcp.NewTool("upsert_rows",
+		mcp.WithDescription("Insert or update records in a header-based table by a key column: rows whose key matches "+
+			"a record are updated in place, records with new keys are appended below the last row, and rows whose key "+
+			"is missing from the records can be deleted. Reports the number of inserted, updated and deleted rows."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet holding the table (optional if range is a defined name or table is given)"),
+		),
+		mcp.WithString("range",
+			mcp.Description("Table range including the header row, e.g. 'A1:F500', or a defined name "+
+				"(default: the whole sheet starting at A1)"),
+		),
+		mcp.WithString("table",
+			mcp.Description("Name of a table to upsert into instead of range; it is extended over appended rows"),
+		),
+		mcp.WithString("key_column",
+			mcp.Required(),
+			mcp.Description("Header of the column that identifies a row, e.g. 'ID'. Text keys match exactly, "+
+				"so '007' and '7' are different keys; numeric keys match numbers of equal value"),
+		),
+		mcp.WithArray("records",
+			mcp.Required(),
+			mcp.Description("Records to upsert, as objects mapping column headers to values. Every record needs a key; "+
+				"columns a record leaves out are not changed. "+
+				"Example: [{\"ID\": 1001, \"Status\": \"shipped\"}, {\"ID\": 1002, \"Status\": \"new\", \"Amount\": 25}]"),
+			mcp.Items(map[string]interface{}{"type": "object"}),
+		),
+		mcp.WithBoolean("delete_missing",
+			mcp.Description("Delete the rows whose key is not in any record, e.g. to mirror a full sync; "+
+				"rows with an empty key are kept (default: false)"),
+		),
+	)
+
+	s.AddTool(upsertRowsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		keyColumn, ok := request.Params.Arguments["key_column"].(string)
+		if !ok || keyColumn == "" {
+			return nil, errors.New("key_column must be a non-empty string")
+		}
+		recordsArg, ok := request.Params.Arguments["records"].([]interface{})
+		if !ok {
+			return nil, errors.New("records must be an array")
+		}
+		records := make([]map[string]interface{}, len(recordsArg))
+		for i, r := range recordsArg {
+			record, ok := r.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("each record must be an object")
+			}
+			records[i] = record
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		cellRange, _ := request.Params.Arguments["range"].(string)
+		tableName, _ := request.Params.Arguments["table"].(string)
+		deleteMissing, _ := request.Params.Arguments["delete_missing"].(bool)
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		var table *sheetTable
+		if tableName != "" {
+			if table, err = findTable(f, sheetName, tableName); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if table.TotalsRows > 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("table '%s' has a totals row; remove it before upserting", table.Name)), nil
+			}
+			if table.HeaderRows == 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("table '%s' has no header row", table.Name)), nil
+			}
+			sheetName, cellRange = table.Sheet, table.Ref
+		} else if sheetName, cellRange, err = resolveRangeRef(f, sheetName, cellRange); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		t, err := readUpsertTable(f, sheetName, cellRange)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read table: %v", err)), nil
+		}
+		counts, err := upsertRecords(f, sheetName, t, table, keyColumn, records, deleteMissing)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to upsert records: %v", err)), nil
+		}
+
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(fmt.Sprintf("Upserted %d records into '%s' by '%s': "+
+			"%d inserted, %d updated, %d unchanged, %d deleted",
+			len(records), sheetName, keyColumn, counts.Inserted, counts.Updated, counts.Unchanged, counts.Deleted)), nil
+	})
+
+	// Tool 47: patch_cells
+	patchCellsTool := mcp.NewTool("patch_cells",
+		mcp.WithDescription("Update scattered cells in one go: set values, formulas and formatting of a list of cells, "+
+			"possibly on different sheets, with a single open and save. Every patch is validated first; if any is "+
+			"invalid, nothing is changed."),
+		mcp.WithString("filepath",
+			mcp.Required(),
+			mcp.Description("Path to the Excel file"),
+		),
+		withFilePassword(),
+		mcp.WithString("sheet_name",
+			mcp.Description("Worksheet of the patches that do not name one"),
+		),
+		mcp.WithArray("patches",
+			mcp.Required(),
+			mcp.Description("Changes to apply, in order. Each patch is an object with 'cell' (e.g. 'B4', 'Summary!C2' "+
+				"or a defined name), an optional 'sheet', and 'value' (null clears the cell) or 'formula' (e.g. '=SUM(B2:B9)'), "+
+				"and/or 'style'. A value may have a 'type' and 'layout' as in write_data_to_excel's column_types. "+
+				"'style' fields (all optional): 'bold', 'italic', 'underline', 'font_size', 'font_family', 'font_color', "+
+				"'bg_color', 'number_format', 'horizontal_align', 'vertical_align', 'wrap_text', 'border_type', "+
+				"'border_color', as in format_range; the cell's other formatting is kept. "+
+				"Example: [{\"cell\": \"B2\", \"value\": 42}, {\"sheet\": \"Summary\", \"cell\": \"C5\", \"formula\": \"=SUM(Data!B:B)\", "+
+				"\"style\": {\"bold\": true, \"number_format\": \"#,##0\"}}, {\"cell\": \"D7\", \"value\": \"2025-06-30\", \"type\": \"date\"}]"),
+			mcp.Items(map[string]interface{}{"type": "object"}),
+		),
+	)
+
+	s.AddTool(patchCellsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		filepath, ok := request.Params.Arguments["filepath"].(string)
+		if !ok {
+			return nil, errors.New("filepath must be a string")
+		}
+		sheetName, _ := request.Params.Arguments["sheet_name"].(string)
+		patchesArg, ok := request.Params.Arguments["patches"].([]interface{})
+		if !ok || len(patchesArg) == 0 {
+			return nil, errors.New("patches must be a non-empty array")
+		}
+		patches := make([]*cellPatch, len(patchesArg))
+		for i, item := range patchesArg {
+			patch, err := parsePatch(item, sheetName)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("patch %d: %v", i+1, err)), nil
+			}
+			patches[i] = patch
+		}
+
+		f, err := openWorkbook(filepath, filePassword(request))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to open Excel file: %v", err)), nil
+		}
+		defer f.Close()
+
+		// Resolve and check every address before changing anything
+		for i, patch := range patches {
+			if refSheet, cell := splitSheetRef(patch.Cell); refSheet != "" {
+				patch.Sheet, patch.Cell = refSheet, cell
+			}
+			sheet, cell, endCell, err := resolveCellRef(f, patch.Sheet, patch.Cell)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("patch %d: %v", i+1, err)), nil
+			}
+			if endCell != "" && endCell != cell {
+				return mcp.NewToolResultError(fmt.Sprintf("patch %d: %q refers to a range, not a single cell", i+1, patch.Cell)), nil
+			}
+			if index, _ := f.GetSheetIndex(sheet); index == -1 {
+				return mcp.NewToolResultError(fmt.Sprintf("patch %d: sheet '%s' not found", i+1, sheet)), nil
+			}
+			if !isWorksheet(f, sheet) {
+				return mcp.NewToolResultError(fmt.Sprintf("patch %d: '%s' is not a worksheet", i+1, sheet)), nil
+			}
+			patch.Sheet, patch.Cell = sheet, cell
+		}
+
+		cells := make([]string, len(patches))
+		for i, patch := range patches {
+			if err := applyPatch(f, patch); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("patch %d: failed to update %s!%s: %v", i+1, patch.Sheet, patch.Cell, err)), nil
+			}
+			cells[i] = fmt.Sprintf("%s!%s", patch.Sheet, patch.Cell)
+		}
+
+		if err := f.Save(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to save workbook: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(fmt.Sprintf("Successfully applied %d patches: %s", len(patches), strings.Join(cells, ", "))), nil
+	})
+
+	// Start the stdio server
+	log.Println("Excel Tools Server starting...")
+	if err := server.ServeStdio(s); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+var borderStyles = map[string]int{
+	"none":             -1,
+	"thin":             1,
+	"medium":           2,
+	"dashed":           3,
+	"dotted":           4,
+	"thick":            5,
+	"double":           6,
+	"hair":             7,
+	"mediumDashed":     8,
+	"dashDot":          9,
+	"mediumDashDot":    10,
+	"dashDotDot":       11,
+	"mediumDashDotDot": 12,
+	"slantDashDot":     13,
+}
+var patterns = map[string]int{
+	"none":       0,
+	"solid":      1,
+	"darkGray":   2,
+	"mediumGray": 3,
+	"lightGray":  4,
+	"gray125":    5,
+	"gray0625":   6,
+}
+
+// pivotSubtotals maps aggregation function names to pivot table subtotal types
+var pivotSubtotals = map[string]string{
+	"sum":     "Sum",
+	"count":   "Count",
+	"average": "Average",
+	"max":     "Max",
+	"min":     "Min",
+}
+
+// findDefinedName looks up a defined name in a scope, a sheet name or "Workbook"
+// ("" for short). Names and scopes match case-insensitively, as in Excel.
+func findDefinedName(f *excelize.File, name, scope string) *excelize.DefinedName {
+	if scope == "" {
+		scope = "Workbook"
+	}
+	for _, dn := range f.GetDefinedName() {
+		if strings.EqualFold(dn.Name, name) && strings.EqualFold(dn.Scope, scope) {
+			return &dn
+		}
+	}
+	return nil
+}
+
+// resolveDefinedName looks up a defined name visible from sheet, preferring sheet-scoped
+// names over workbook-scoped ones, and returns the sheet and range it refers to
+func resolveDefinedName(f *excelize.File, sheet, name string) (string, string, bool) {
+	var refersTo string
+	found := false
+	for _, dn := range f.GetDefinedName() {
+		if !strings.EqualFold(dn.Name, name) {
+			continue
+		}
+		if sheet != "" && dn.Scope == sheet {
+			refersTo, found = dn.RefersTo, true
+			break
+		}
+		if dn.Scope == "Workbook" {
+			refersTo, found = dn.RefersTo, true
+		}
+	}
+	if !found {
+		return "", "", false
+	}
+	refSheet, cellRange := splitSheetRef(refersTo)
+	if refSheet == "" {
+		refSheet = sheet
+	}
+	return refSheet, cellRange, true
+}
+
+// resolveCellRef resolves a cell reference or defined name to a sheet and the
+// start and end cells of its range. endCell is "" for plain cell references.
+func resolveCellRef(f *excelize.File, sheet, ref string) (targetSheet, startCell, endCell string, err error) {
+	if _, _, err := excelize.CellNameToCoordinates(ref); err == nil {
+		if sheet == "" {
+			return "", "", "", errors.New("sheet_name is required unless a defined name is used")
+		}
+		return sheet, ref, "", nil
+	}
+	refSheet, cellRange, ok := resolveDefinedName(f, sheet, ref)
+	if !ok {
+		return "", "", "", fmt.Errorf("%q is neither a cell reference nor a defined name", ref)
+	}
+	if refSheet == "" {
+		return "", "", "", fmt.Errorf("defined name %q does not refer to a worksheet range", ref)
+	}
+	parts := strings.Split(cellRange, ":")
+	if _, _, err := excelize.CellNameToCoordinates(parts[0]); err != nil || len(parts) > 2 {
+		return "", "", "", fmt.Errorf("defined name %q does not refer to a cell range", ref)
+	}
+	if len(parts) == 2 {
+		endCell = parts[1]
+	}
+	return refSheet, parts[0], endCell, nil
+}
+
+// resolveRangeRef resolves a range argument that may be a defined name to its
+// sheet and range. An empty range is passed through for the caller's default.
+func resolveRangeRef(f *excelize.File, sheet, cellRange string) (string, string, error) {
+	if cellRange != "" {
+		if _, _, _, _, err := parseRangeRef(cellRange); err != nil {
+			refSheet, refRange, ok := resolveDefinedName(f, sheet, cellRange)
+			if !ok || refSheet == "" {
+				return "", "", fmt.Errorf("%q is neither a cell range nor a defined name", cellRange)
+			}
+			sheet, cellRange = refSheet, refRange
+		}
+	}
+	if sheet == "" {
+		return "", "", errors.New("sheet_name is required unless range is a defined name")
+	}
+	return sheet, cellRange, nil
+}
+
+// sameFile reports whether two paths refer to the same file
+func sameFile(a, b string) bool {
+	if a == b {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// isWorksheet reports whether sheet is a regular worksheet rather than a chart, dialog
+// or macro sheet, by the type of its part. Sheets not in the package yet are worksheets.
+func isWorksheet(f *excelize.File, sheet string) bool {
+	for name, part := range sheetParts(f) {
+		if strings.EqualFold(name, sheet) {
+			return part.Type == "" || part.Type == "worksheet"
+		}
+	}
+	return true
+}
+
+// toStringSlice converts an optional JSON array argument to a string slice
+func toStringSlice(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("must be an array of strings")
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		str, ok := item.(string)
+		if !ok {
+			return nil, errors.New("must be an array of strings")
+		}
+		result = append(result, str)
+	}
+	return result, nil
 }
 
 // parseNumberFormat converts number format strings to Excel format codes
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// mergeSource selects the sheets of one workbook to merge. No sheets means every worksheet.
+type mergeSource struct {
+	Filepath string
+	Sheets   []string
+	Password string
+}
+
+// mergedSheet records where a source sheet ended up in the merged workbook
+type mergedSheet struct {
+	Source string
+	Sheet  string
+	Target string
+	Rows   int
+}
+
+// placeholderSheet names the default sheet of a new workbook until it is removed
+const placeholderSheet = "__placeholder__"
+
+// uniqueSheetName returns name, or name with a " (n)" suffix if a sheet with that
+// name already exists, keeping within Excel's 31-character limit
+func uniqueSheetName(f *excelize.File, name string) string {
+	candidate := name
+	for n := 2; ; n++ {
+		if index, _ := f.GetSheetIndex(candidate); index == -1 {
+			return candidate
+		}
+		suffix := fmt.Sprintf(" (%d)", n)
+		base := []rune(name)
+		if len(base)+len(suffix) > excelize.MaxSheetNameLength {
+			base = base[:excelize.MaxSheetNameLength-len(suffix)]
+		}
+		candidate = string(base) + suffix
+	}
+}
+
+// selectedSheets resolves the sheets to merge from a source workbook, skipping chart sheets
+func selectedSheets(f *excelize.File, source mergeSource) ([]string, error) {
+	if len(source.Sheets) == 0 {
+		var sheets []string
+		for _, sheet := range f.GetSheetList() {
+			if isWorksheet(f, sheet) {
+				sheets = append(sheets, sheet)
+			}
+		}
+		return sheets, nil
+	}
+	for _, sheet := range source.Sheets {
+		if index, _ := f.GetSheetIndex(sheet); index == -1 {
+			return nil, fmt.Errorf("worksheet '%s' not found in %s", sheet, source.Filepath)
+		}
+		if !isWorksheet(f, sheet) {
+			return nil, fmt.Errorf("'%s' in %s is not a worksheet", sheet, source.Filepath)
+		}
+	}
+	return source.Sheets, nil
+}
+
+// mergeWorkbooks combines the selected sheets of the sources into a new workbook.
+// Each sheet is copied as its own worksheet, renamed on conflict, or, if stackSheet
+// is set, the sheets are read as header-based tables with the same columns and
+// stacked into a single sheet with sourceColumn naming the origin of each row.
+func mergeWorkbooks(sources []mergeSource, stackSheet, sourceColumn string) (*excelize.File, []mergedSheet, error) {
+	out := excelize.NewFile()
+	if err := out.SetSheetName("Sheet1", placeholderSheet); err != nil {
+		out.Close()
+		return nil, nil, err
+	}
+
+	var merged []mergedSheet
+	stack := &tableStack{sheet: stackSheet, sourceColumn: sourceColumn}
+	for _, source := range sources {
+		src, err := openWorkbook(source.Filepath, source.Password)
+		if err != nil {
+			out.Close()
+			return nil, nil, fmt.Errorf("failed to open %s: %w", source.Filepath, err)
+		}
+		sheets, err := selectedSheets(src, source)
+		if err == nil {
+			for _, sheet := range sheets {
+				entry := mergedSheet{Source: source.Filepath, Sheet: sheet}
+				if stackSheet != "" {
+					label := filepath.Base(source.Filepath)
+					if len(sheets) > 1 {
+						label += "!" + sheet
+					}
+					entry.Target = stackSheet
+					entry.Rows, err = stack.add(src, sheet, out, label)
+				} else {
+					entry.Target = uniqueSheetName(out, sheet)
+					if err = copyWorksheetAcross(src, sheet, out, entry.Target); err == nil {
+						err = setSheetVisibility(out, entry.Target, sheetVisibility(src, sheet))
+					}
+				}
+				if err != nil {
+					err = fmt.Errorf("failed to merge '%s' from %s: %w", sheet, source.Filepath, err)
+					break
+				}
+				merged = append(merged, entry)
+			}
+		}
+		src.Close()
+		if err != nil {
+			out.Close()
+			return nil, nil, err
+		}
+	}
+	if len(merged) == 0 {
+		out.Close()
+		return nil, nil, fmt.Errorf("no worksheets to merge")
+	}
+
+	if err := out.DeleteSheet(placeholderSheet); err != nil {
+		out.Close()
+		return nil, nil, err
+	}
+	out.SetActiveSheet(0)
+	return out, merged, nil
+}
+
+// tableStack appends same-shaped tables to one sheet. The first table fixes the
+// columns, header styles, data column styles and column widths.
+type tableStack struct {
+	sheet        string
+	sourceColumn string
+	columns      []string
+	nextRow      int
+}
+
+// add appends the table on sheet of src to the stack and returns the number of rows added
+func (s *tableStack) add(src *excelize.File, sheet string, out *excelize.File, label string) (int, error) {
+	table, err := readTable(src, sheet, "")
+	if err != nil {
+		return 0, err
+	}
+	if s.columns == nil {
+		if err := s.start(src, sheet, out, table.Columns); err != nil {
+			return 0, err
+		}
+	}
+
+	// Match columns by name so that tables with reordered columns still stack
+	mismatch := fmt.Errorf("columns [%s] do not match [%s]",
+		strings.Join(table.Columns, ", "), strings.Join(s.columns, ", "))
+	if len(table.Columns) != len(s.columns) {
+		return 0, mismatch
+	}
+	order := make([]int, len(s.columns))
+	for i, column := range s.columns {
+		order[i] = slices.Index(table.Columns, column)
+		if order[i] == -1 {
+			return 0, mismatch
+		}
+	}
+
+	// Cells are copied rather than rewritten from the table values so that they keep
+	// their types, formulas and styles
+	styles := &styleCopier{src: src, dst: out, ids: make(map[int]int)}
+	for _, rowNum := range table.RowNums {
+		cell, _ := excelize.CoordinatesToCellName(1, s.nextRow)
+		if err := out.SetCellStr(s.sheet, cell, label); err != nil {
+			return 0, err
+		}
+		if err := copyTableRow(styles, sheet, 1, rowNum, s.sheet, 2, s.nextRow, order); err != nil {
+			return 0, err
+		}
+		s.nextRow++
+	}
+	return len(table.Rows), nil
+}
+
+// start creates the stack sheet with the header and layout of the first table
+func (s *tableStack) start(src *excelize.File, sheet string, out *excelize.File, columns []string) error {
+	s.columns = columns
+	s.nextRow = 2
+	if _, err := out.NewSheet(s.sheet); err != nil {
+		return err
+	}
+	format, err := readTableFormat(src, sheet, 1, 1, len(columns))
+	if err != nil {
+		return err
+	}
+	// Table columns shift one to the right to make room for the source column
+	if err := format.applyColumns(out, s.sheet, 2); err != nil {
+		return err
+	}
+	header := append([]interface{}{s.sourceColumn}, toInterfaceSlice(columns)...)
+	if err := out.SetSheetRow(s.sheet, "A1", &header); err != nil {
+		return err
+	}
+	if err := format.applyHeader(out, s.sheet, 2); err != nil {
+		return err
+	}
+	// The source column header takes the style of the first header cell
+	styleID, err := format.styles(out).styleID(format.headerStyles[0])
+	if err != nil {
+		return err
+	}
+	return out.SetCellStyle(s.sheet, "A1", "A1", styleID)
+}
+
+// tableFormat holds the header cell styles, data column styles and column widths
+// of a table so that they can be reproduced in another sheet or workbook
+type tableFormat struct {
+	src          *excelize.File
+	sheet        string
+	col          int
+	headerStyles []int
+	dataStyles   []int
+	widths       []float64
+	copiers      map[*excelize.File]*styleCopier
+}
+
+// readTableFormat reads the formatting of a table of width columns whose header
+// row starts at the 1-based column col of row. Data column styles are taken from
+// the first data row.
+func readTableFormat(f *excelize.File, sheet string, col, row, width int) (*tableFormat, error) {
+	format := &tableFormat{
+		src:          f,
+		sheet:        sheet,
+		col:          col,
+		headerStyles: make([]int, width),
+		dataStyles:   make([]int, width),
+		widths:       make([]float64, width),
+		copiers:      make(map[*excelize.File]*styleCopier),
+	}
+	for i := 0; i < width; i++ {
+		headerCell, _ := excelize.CoordinatesToCellName(col+i, row)
+		dataCell, _ := excelize.CoordinatesToCellName(col+i, row+1)
+		var err error
+		if format.headerStyles[i], err = f.GetCellStyle(sheet, headerCell); err != nil {
+			return nil, err
+		}
+		if format.dataStyles[i], err = f.GetCellStyle(sheet, dataCell); err != nil {
+			return nil, err
+		}
+	}
+	layout, err := readWorksheetLayout(f, sheet)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range layout.Cols {
+		for i := range format.widths {
+			if col+i >= c.Min && col+i <= c.Max {
+				format.widths[i] = c.Width
+			}
+		}
+	}
+	return format, nil
+}
+
+// styles returns the style copier from the table's workbook to dst
+func (t *tableFormat) styles(dst *excelize.File) *styleCopier {
+	if t.copiers[dst] == nil {
+		t.copiers[dst] = &styleCopier{src: t.src, dst: dst, ids: make(map[int]int)}
+	}
+	return t.copiers[dst]
+}
+
+// applyColumns sets the column widths and data column styles of a table whose
+// first column is the 1-based column startCol of sheet
+func (t *tableFormat) applyColumns(dst *excelize.File, sheet string, startCol int) error {
+	for i := range t.widths {
+		colName, _ := excelize.ColumnNumberToName(startCol + i)
+		if t.widths[i] > 0 {
+			if err := dst.SetColWidth(sheet, colName, colName, t.widths[i]); err != nil {
+				return err
+			}
+		}
+		styleID, err := t.styles(dst).styleID(t.dataStyles[i])
+		if err != nil {
+			return err
+		}
+		if styleID != 0 {
+			if err := dst.SetColStyle(sheet, colName, styleID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyHeader styles the header cells in row 1 of a table whose first column is
+// the 1-based column startCol of sheet
+func (t *tableFormat) applyHeader(dst *excelize.File, sheet string, startCol int) error {
+	for i := range t.headerStyles {
+		styleID, err := t.styles(dst).styleID(t.headerStyles[i])
+		if err != nil {
+			return err
+		}
+		cell, _ := excelize.CoordinatesToCellName(startCol+i, 1)
+		if err := dst.SetCellStyle(sheet, cell, cell, styleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toInterfaceSlice converts a string slice to a slice of interface values
+func toInterfaceSlice(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// mergedArea is a merged cell range and the value shown in it
+type mergedArea struct {
+	Sheet string `json:"sheet"`
+	Range string `json:"range"`
+	Value string `json:"value"`
+}
+
+// cellContent is the value of a single cell
+type cellContent struct {
+	Cell  string `json:"cell"`
+	Value string `json:"value"`
+}
+
+// mergeCheck reports what merging a range would do
+type mergeCheck struct {
+	Sheet             string        `json:"sheet"`
+	Range             string        `json:"range"`
+	KeptCell          string        `json:"kept_cell"`
+	KeptValue         string        `json:"kept_value"`
+	DiscardedCells    []cellContent `json:"discarded_cells"`
+	OverlappingMerges []string      `json:"overlapping_merges"`
+	Safe              bool          `json:"safe"`
+}
+
+// listMergedAreas lists the merged cell ranges of a sheet
+func listMergedAreas(f *excelize.File, sheet string) ([]mergedArea, error) {
+	mergeCells, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, err
+	}
+	areas := make([]mergedArea, 0, len(mergeCells))
+	for _, mc := range mergeCells {
+		areas = append(areas, mergedArea{
+			Sheet: sheet,
+			Range: mc.GetStartAxis() + ":" + mc.GetEndAxis(),
+			Value: mc.GetCellValue(),
+		})
+	}
+	return areas, nil
+}
+
+// rangesOverlap reports whether two A1-style ranges share at least one cell
+func rangesOverlap(a, b string) bool {
+	ac1, ar1, ac2, ar2, err := parseRangeRef(a)
+	if err != nil {
+		return false
+	}
+	bc1, br1, bc2, br2, err := parseRangeRef(b)
+	if err != nil {
+		return false
+	}
+	return ac1 <= bc2 && bc1 <= ac2 && ar1 <= br2 && br1 <= ar2
+}
+
+// checkMerge reports the non-empty cells whose contents a merge of cellRange would
+// discard, since only the top-left cell is kept, and the existing merged areas
+// that overlap it and would be removed
+func checkMerge(f *excelize.File, sheet, cellRange string) (*mergeCheck, error) {
+	col1, row1, col2, row2, err := parseRangeRef(cellRange)
+	if err != nil {
+		return nil, err
+	}
+	keptCell, _ := excelize.CoordinatesToCellName(col1, row1)
+	endCell, _ := excelize.CoordinatesToCellName(col2, row2)
+	check := &mergeCheck{
+		Sheet:             sheet,
+		Range:             keptCell + ":" + endCell,
+		KeptCell:          keptCell,
+		DiscardedCells:    []cellContent{},
+		OverlappingMerges: []string{},
+	}
+	areas, err := listMergedAreas(f, sheet)
+	if err != nil {
+		return nil, err
+	}
+	for _, area := range areas {
+		if rangesOverlap(area.Range, check.Range) {
+			check.OverlappingMerges = append(check.OverlappingMerges, area.Range)
+		}
+	}
+
+	// Cell reads inside merged areas return the merged value, so stream the stored values instead
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for row := 1; row <= row2 && rows.Next(); row++ {
+		cols, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+		if row < row1 {
+			continue
+		}
+		for col := col1; col <= col2; col++ {
+			cell, _ := excelize.CoordinatesToCellName(col, row)
+			value := cellAt(cols, col)
+			if value == "" && !inMergedArea(areas, cell) {
+				if formula, _ := f.GetCellFormula(sheet, cell); formula != "" {
+					value = "=" + formula
+				}
+			}
+			switch {
+			case cell == keptCell:
+				check.KeptValue = value
+			case value != "":
+				check.DiscardedCells = append(check.DiscardedCells, cellContent{Cell: cell, Value: value})
+			}
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return nil, err
+	}
+	check.Safe = len(check.DiscardedCells) == 0 && len(check.OverlappingMerges) == 0
+	return check, nil
+}
+
+// inMergedArea reports whether cell lies inside one of the merged areas
+func inMergedArea(areas []mergedArea, cell string) bool {
+	for _, area := range areas {
+		if rangesOverlap(area.Range, cell+":"+cell) {
+			return true
+		}
+	}
+	return false
+}
+
+// unmergeAreas unmerges every merged area that overlaps cellRange. With fill, the
+// value of each area's top-left cell is written into all of its former cells.
+func unmergeAreas(f *excelize.File, sheet, cellRange string, fill bool) ([]string, error) {
+	areas, err := listMergedAreas(f, sheet)
+	if err != nil {
+		return nil, err
+	}
+	var unmerged []string
+	for _, area := range areas {
+		if !rangesOverlap(area.Range, cellRange) {
+			continue
+		}
+		col1, row1, col2, row2, _ := parseRangeRef(area.Range)
+		topLeft, _ := excelize.CoordinatesToCellName(col1, row1)
+		bottomRight, _ := excelize.CoordinatesToCellName(col2, row2)
+		if err := f.UnmergeCell(sheet, topLeft, bottomRight); err != nil {
+			return nil, err
+		}
+		if fill {
+			if err := fillFromCell(f, sheet, topLeft, col1, row1, col2, row2); err != nil {
+				return nil, fmt.Errorf("failed to fill %s: %w", area.Range, err)
+			}
+		}
+		unmerged = append(unmerged, area.Range)
+	}
+	return unmerged, nil
+}
+
+// fillFromCell writes the value of source into every other cell of the given
+// rectangle, keeping its type. Formulas are filled with their calculated value.
+func fillFromCell(f *excelize.File, sheet, source string, col1, row1, col2, row2 int) error {
+	cellType, err := f.GetCellType(sheet, source)
+	if err != nil {
+		return err
+	}
+	raw, err := f.GetCellValue(sheet, source, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return err
+	}
+	if raw == "" {
+		return nil
+	}
+	for row := row1; row <= row2; row++ {
+		for col := col1; col <= col2; col++ {
+			cell, _ := excelize.CoordinatesToCellName(col, row)
+			if cell == source {
+				continue
+			}
+			switch value := parseCellValue(raw); {
+			case cellType == excelize.CellTypeBool:
+				err = f.SetCellBool(sheet, cell, raw == "1" || raw == "TRUE")
+			case cellType == excelize.CellTypeSharedString, cellType == excelize.CellTypeInlineString:
+				err = f.SetCellStr(sheet, cell, raw)
+			default:
+				err = f.SetCellValue(sheet, cell, value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
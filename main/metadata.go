@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetDimensions describes the area of a worksheet that holds data
+type sheetDimensions struct {
+	UsedRange       string `json:"used_range,omitempty"`
+	StoredDimension string `json:"stored_dimension,omitempty"`
+	FirstRow        int    `json:"first_row,omitempty"`
+	LastRow         int    `json:"last_row,omitempty"`
+	FirstColumn     string `json:"first_column,omitempty"`
+	LastColumn      string `json:"last_column,omitempty"`
+	RowCount        int    `json:"row_count"`
+	ColumnCount     int    `json:"column_count"`
+	NonEmptyCells   int    `json:"non_empty_cells"`
+}
+
+// getSheetDimensions computes the bounding box of the non-empty cells of a sheet
+// in a single streaming pass. The stored dimension is the <dimension> reference
+// saved in the file, which other applications may leave stale.
+func getSheetDimensions(f *excelize.File, sheet string) (*sheetDimensions, error) {
+	dims := &sheetDimensions{}
+	if ref, err := f.GetSheetDimension(sheet); err == nil {
+		dims.StoredDimension = ref
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	firstCol, lastCol := 0, 0
+	for rowNum := 1; rows.Next(); rowNum++ {
+		cols, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, err
+		}
+		for i, value := range cols {
+			if value == "" {
+				continue
+			}
+			dims.NonEmptyCells++
+			if dims.FirstRow == 0 {
+				dims.FirstRow = rowNum
+			}
+			dims.LastRow = rowNum
+			if firstCol == 0 || i+1 < firstCol {
+				firstCol = i + 1
+			}
+			if i+1 > lastCol {
+				lastCol = i + 1
+			}
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return nil, err
+	}
+	if dims.NonEmptyCells == 0 {
+		return dims, nil
+	}
+
+	dims.FirstColumn, _ = excelize.ColumnNumberToName(firstCol)
+	dims.LastColumn, _ = excelize.ColumnNumberToName(lastCol)
+	dims.RowCount = dims.LastRow - dims.FirstRow + 1
+	dims.ColumnCount = lastCol - firstCol + 1
+	startCell, _ := excelize.CoordinatesToCellName(firstCol, dims.FirstRow)
+	endCell, _ := excelize.CoordinatesToCellName(lastCol, dims.LastRow)
+	dims.UsedRange = startCell + ":" + endCell
+	return dims, nil
+}
+
+// documentProperties holds the core document properties of a workbook
+type documentProperties struct {
+	Title          string `json:"title,omitempty"`
+	Subject        string `json:"subject,omitempty"`
+	Creator        string `json:"creator,omitempty"`
+	Keywords       string `json:"keywords,omitempty"`
+	Description    string `json:"description,omitempty"`
+	LastModifiedBy string `json:"last_modified_by,omitempty"`
+	Category       string `json:"category,omitempty"`
+	ContentStatus  string `json:"content_status,omitempty"`
+	Identifier     string `json:"identifier,omitempty"`
+	Language       string `json:"language,omitempty"`
+	Revision       string `json:"revision,omitempty"`
+	Version        string `json:"version,omitempty"`
+	Created        string `json:"created,omitempty"`
+	Modified       string `json:"modified,omitempty"`
+}
+
+// newDocumentProperties converts excelize core properties to their JSON form
+func newDocumentProperties(props *excelize.DocProperties) *documentProperties {
+	return &documentProperties{
+		Title:          props.Title,
+		Subject:        props.Subject,
+		Creator:        props.Creator,
+		Keywords:       props.Keywords,
+		Description:    props.Description,
+		LastModifiedBy: props.LastModifiedBy,
+		Category:       props.Category,
+		ContentStatus:  props.ContentStatus,
+		Identifier:     props.Identifier,
+		Language:       props.Language,
+		Revision:       props.Revision,
+		Version:        props.Version,
+		Created:        props.Created,
+		Modified:       props.Modified,
+	}
+}
+
+// applicationProperties holds the extended (app) properties of a workbook
+type applicationProperties struct {
+	Application       string `json:"application,omitempty"`
+	AppVersion        string `json:"app_version,omitempty"`
+	Company           string `json:"company,omitempty"`
+	DocSecurity       int    `json:"doc_security"`
+	ScaleCrop         bool   `json:"scale_crop"`
+	LinksUpToDate     bool   `json:"links_up_to_date"`
+	HyperlinksChanged bool   `json:"hyperlinks_changed"`
+}
+
+// newApplicationProperties converts excelize app properties to their JSON form
+func newApplicationProperties(props *excelize.AppProperties) *applicationProperties {
+	return &applicationProperties{
+		Application:       props.Application,
+		AppVersion:        props.AppVersion,
+		Company:           props.Company,
+		DocSecurity:       props.DocSecurity,
+		ScaleCrop:         props.ScaleCrop,
+		LinksUpToDate:     props.LinksUpToDate,
+		HyperlinksChanged: props.HyperlinksChanged,
+	}
+}
+
+// sheetDescription describes the layout and objects of one sheet
+type sheetDescription struct {
+	Name             string   `json:"name"`
+	Index            int      `json:"index"`
+	Type             string   `json:"type"`
+	Visibility       string   `json:"visibility"`
+	Active           bool     `json:"active"`
+	TabColor         string   `json:"tab_color,omitempty"`
+	DefaultRowHeight float64  `json:"default_row_height,omitempty"`
+	MergedCells      []string `json:"merged_cells"`
+	TableCount       int      `json:"table_count"`
+	ChartCount       int      `json:"chart_count"`
+	ImageCount       int      `json:"image_count"`
+	PivotTableCount  int      `json:"pivot_table_count"`
+	DefinedNames     []string `json:"defined_names,omitempty"`
+}
+
+// packageRelationships is the minimal shape of an OPC relationships part
+type packageRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// readRelationships parses the relationships part of a package part as stored in the file
+func readRelationships(f *excelize.File, part string) *packageRelationships {
+	relsPart := "_rels/.rels"
+	if part != "" {
+		relsPart = path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+	}
+	rels := &packageRelationships{}
+	if data, ok := f.Pkg.Load(relsPart); ok {
+		if content, ok := data.([]byte); ok {
+			_ = xml.Unmarshal(content, rels)
+		}
+	}
+	return rels
+}
+
+// resolvePartTarget resolves a relationship target relative to the part that owns it
+func resolvePartTarget(part, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Join(path.Dir(part), target)
+}
+
+// workbookPartPath returns the path of the workbook part, usually "xl/workbook.xml"
+func workbookPartPath(f *excelize.File) string {
+	for _, rel := range readRelationships(f, "").Relationships {
+		if strings.HasSuffix(rel.Type, "/officeDocument") {
+			return resolvePartTarget("", rel.Target)
+		}
+	}
+	return "xl/workbook.xml"
+}
+
+// sheetPart locates a sheet in the package, e.g. "xl/worksheets/sheet1.xml" of type "worksheet"
+type sheetPart struct {
+	Path string
+	Type string
+}
+
+// sheetParts maps each sheet name to its package part
+func sheetParts(f *excelize.File) map[string]sheetPart {
+	parts := make(map[string]sheetPart)
+	f.GetSheetList() // ensure the workbook part is loaded
+	workbookPart := workbookPartPath(f)
+	targets := make(map[string]sheetPart)
+	for _, rel := range readRelationships(f, workbookPart).Relationships {
+		targets[rel.ID] = sheetPart{Path: resolvePartTarget(workbookPart, rel.Target), Type: path.Base(rel.Type)}
+	}
+	if f.WorkBook != nil {
+		for _, sheet := range f.WorkBook.Sheets.Sheet {
+			parts[sheet.Name] = targets[sheet.ID]
+		}
+	}
+	return parts
+}
+
+// countSheetCharts counts the charts embedded in the drawing of a sheet part
+func countSheetCharts(f *excelize.File, sheetPart string) int {
+	count := 0
+	for _, rel := range readRelationships(f, sheetPart).Relationships {
+		if path.Base(rel.Type) != "drawing" {
+			continue
+		}
+		drawingPart := resolvePartTarget(sheetPart, rel.Target)
+		for _, drawingRel := range readRelationships(f, drawingPart).Relationships {
+			if path.Base(drawingRel.Type) == "chart" {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// describeSheets describes every sheet of the workbook in tab order
+func describeSheets(f *excelize.File) ([]sheetDescription, error) {
+	parts := sheetParts(f)
+	definedNames := f.GetDefinedName()
+	activeIndex := f.GetActiveSheetIndex()
+
+	var sheets []sheetDescription
+	for i, name := range f.GetSheetList() {
+		desc := sheetDescription{
+			Name:        name,
+			Index:       i,
+			Type:        parts[name].Type,
+			Visibility:  sheetVisibility(f, name),
+			Active:      i == activeIndex,
+			MergedCells: []string{},
+		}
+		if desc.Type == "" {
+			desc.Type = "worksheet"
+		}
+		for _, dn := range definedNames {
+			if dn.Scope == name {
+				desc.DefinedNames = append(desc.DefinedNames, dn.Name)
+			}
+		}
+		desc.ChartCount = countSheetCharts(f, parts[name].Path)
+		if desc.Type != "worksheet" {
+			sheets = append(sheets, desc)
+			continue
+		}
+
+		props, err := f.GetSheetProps(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read properties of '%s': %w", name, err)
+		}
+		switch {
+		case props.TabColorRGB != nil && *props.TabColorRGB != "":
+			desc.TabColor = *props.TabColorRGB
+		case props.TabColorTheme != nil:
+			desc.TabColor = fmt.Sprintf("theme:%d", *props.TabColorTheme)
+		case props.TabColorIndexed != nil:
+			desc.TabColor = fmt.Sprintf("indexed:%d", *props.TabColorIndexed)
+		}
+		if props.DefaultRowHeight != nil {
+			desc.DefaultRowHeight = *props.DefaultRowHeight
+		}
+
+		mergeCells, err := f.GetMergeCells(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read merged cells of '%s': %w", name, err)
+		}
+		for _, mc := range mergeCells {
+			desc.MergedCells = append(desc.MergedCells, mc.GetStartAxis()+":"+mc.GetEndAxis())
+		}
+		tables, err := f.GetTables(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tables of '%s': %w", name, err)
+		}
+		desc.TableCount = len(tables)
+		pictureCells, err := f.GetPictureCells(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pictures of '%s': %w", name, err)
+		}
+		for _, cell := range pictureCells {
+			pictures, err := f.GetPictures(name, cell)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read pictures of '%s': %w", name, err)
+			}
+			desc.ImageCount += len(pictures)
+		}
+		if pivotTables, err := f.GetPivotTables(name); err == nil {
+			desc.PivotTableCount = len(pivotTables)
+		}
+		sheets = append(sheets, desc)
+	}
+	return sheets, nil
+}
+
+// customPropertyValue converts a JSON value to a custom property value: whole
+// numbers become 4-byte integers and ISO 8601 strings become date-times
+func customPropertyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+			return int32(v)
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+	}
+	return value
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// cellStyleChange is the formatting a patch applies to a cell. Unset fields keep the cell's current formatting.
+type cellStyleChange struct {
+	Bold            *bool   `json:"bold"`
+	Italic          *bool   `json:"italic"`
+	Underline       string  `json:"underline"`
+	FontSize        float64 `json:"font_size"`
+	FontFamily      string  `json:"font_family"`
+	FontColor       string  `json:"font_color"`
+	BgColor         string  `json:"bg_color"`
+	NumberFormat    string  `json:"number_format"`
+	HorizontalAlign string  `json:"horizontal_align"`
+	VerticalAlign   string  `json:"vertical_align"`
+	WrapText        *bool   `json:"wrap_text"`
+	BorderType      string  `json:"border_type"`
+	BorderColor     string  `json:"border_color"`
+}
+
+// validate checks the values of the style change that excelize would otherwise reject when applying it
+func (c *cellStyleChange) validate() error {
+	if c.BorderType != "" {
+		if _, ok := borderStyles[c.BorderType]; !ok {
+			return fmt.Errorf("invalid border type: %s", c.BorderType)
+		}
+	}
+	if c.FontSize < 0 || c.FontSize > excelize.MaxFontSize {
+		return fmt.Errorf("font_size must be between 1 and %d", excelize.MaxFontSize)
+	}
+	return nil
+}
+
+// apply overlays the style change on a cell's style
+func (c *cellStyleChange) apply(style *excelize.Style) {
+	if style.Font == nil {
+		style.Font = &excelize.Font{}
+	}
+	if c.Bold != nil {
+		style.Font.Bold = *c.Bold
+	}
+	if c.Italic != nil {
+		style.Font.Italic = *c.Italic
+	}
+	if c.Underline != "" {
+		style.Font.Underline = c.Underline
+	}
+	if c.FontSize > 0 {
+		style.Font.Size = c.FontSize
+	}
+	if c.FontFamily != "" {
+		style.Font.Family = c.FontFamily
+	}
+	if c.FontColor != "" {
+		style.Font.Color = c.FontColor
+	}
+	if c.BgColor != "" {
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{c.BgColor}, Pattern: 1}
+	}
+	if c.NumberFormat != "" {
+		style.NumFmt = 0
+		style.CustomNumFmt = &c.NumberFormat
+	}
+	if c.HorizontalAlign != "" || c.VerticalAlign != "" || c.WrapText != nil {
+		if style.Alignment == nil {
+			style.Alignment = &excelize.Alignment{}
+		}
+		if c.HorizontalAlign != "" {
+			style.Alignment.Horizontal = c.HorizontalAlign
+		}
+		if c.VerticalAlign != "" {
+			style.Alignment.Vertical = c.VerticalAlign
+		}
+		if c.WrapText != nil {
+			style.Alignment.WrapText = *c.WrapText
+		}
+	}
+	if c.BorderType != "" {
+		style.Border = nil
+		for _, side := range []string{"top", "right", "bottom", "left"} {
+			style.Border = append(style.Border, excelize.Border{Type: side, Style: borderStyles[c.BorderType], Color: c.BorderColor})
+		}
+	}
+}
+
+// cellPatch is one change to one cell: a value or a formula, a style change, or both
+type cellPatch struct {
+	Sheet    string
+	Cell     string
+	HasValue bool
+	Value    interface{}
+	Formula  string
+	Style    *cellStyleChange
+}
+
+// parsePatch parses one item of the patches argument. Patches without a sheet use defaultSheet.
+func parsePatch(item interface{}, defaultSheet string) (*cellPatch, error) {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("must be an object")
+	}
+	p := &cellPatch{Sheet: defaultSheet}
+	if sheet, ok := fields["sheet"].(string); ok && sheet != "" {
+		p.Sheet = sheet
+	}
+	p.Cell, _ = fields["cell"].(string)
+	if p.Cell == "" {
+		return nil, errors.New("cell is required")
+	}
+	var typ columnType
+	for key, value := range fields {
+		switch key {
+		case "sheet", "cell":
+		case "value":
+			p.HasValue, p.Value = true, value
+		case "formula":
+			formula, ok := value.(string)
+			if !ok || formula == "" {
+				return nil, errors.New("formula must be a non-empty string")
+			}
+			p.Formula = formula
+		case "type":
+			typ.Type, _ = value.(string)
+		case "layout":
+			typ.Layout, _ = value.(string)
+		case "style":
+			styleJSON, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid style: %w", err)
+			}
+			p.Style = &cellStyleChange{}
+			if err := json.Unmarshal(styleJSON, p.Style); err != nil {
+				return nil, fmt.Errorf("invalid style: %v", err)
+			}
+			if err := p.Style.validate(); err != nil {
+				return nil, fmt.Errorf("invalid style: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown field %q", key)
+		}
+	}
+	if p.HasValue && p.Formula != "" {
+		return nil, errors.New("set either value or formula, not both")
+	}
+	if !p.HasValue && p.Formula == "" && p.Style == nil {
+		return nil, errors.New("set value, formula or style")
+	}
+	if p.HasValue {
+		switch p.Value.(type) {
+		case nil, string, float64, bool:
+		default:
+			return nil, errors.New("value must be a string, number, boolean or null")
+		}
+	}
+	if typ.Type != "" || typ.Layout != "" {
+		if !p.HasValue {
+			return nil, errors.New("type and layout apply to value")
+		}
+		if err := typ.normalize(); err != nil {
+			return nil, err
+		}
+		if typ.Type == "formula" {
+			return nil, errors.New("use formula instead of a value of type formula")
+		}
+		var err error
+		if p.Value, err = typ.convert(p.Value); err != nil {
+			return nil, err
+		}
+		if format := typ.numberFormat(p.Value); format != "" && (p.Style == nil || p.Style.NumberFormat == "") {
+			if p.Style == nil {
+				p.Style = &cellStyleChange{}
+			}
+			p.Style.NumberFormat = format
+		}
+	}
+	return p, nil
+}
+
+// applyPatch writes the value or formula of a patch and applies its style change
+func applyPatch(f *excelize.File, p *cellPatch) error {
+	if p.HasValue {
+		if err := f.SetCellValue(p.Sheet, p.Cell, p.Value); err != nil {
+			return err
+		}
+	}
+	if p.Formula != "" {
+		if err := setFormula(f, p.Sheet, p.Cell, p.Formula); err != nil {
+			return err
+		}
+	}
+	if p.Style == nil {
+		return nil
+	}
+	styleID, err := f.GetCellStyle(p.Sheet, p.Cell)
+	if err != nil {
+		return err
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		return err
+	}
+	p.Style.apply(style)
+	if styleID, err = f.NewStyle(style); err != nil {
+		return err
+	}
+	return f.SetCellStyle(p.Sheet, p.Cell, p.Cell, styleID)
+}
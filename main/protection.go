@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// protectionAlgorithm hashes protection passwords the way current Excel versions do
+const protectionAlgorithm = "SHA-512"
+
+// sheetPermissions maps the actions that can be allowed on a protected sheet to their options
+func sheetPermissions(opts *excelize.SheetProtectionOptions) map[string]*bool {
+	return map[string]*bool{
+		"format_cells":      &opts.FormatCells,
+		"format_columns":    &opts.FormatColumns,
+		"format_rows":       &opts.FormatRows,
+		"insert_columns":    &opts.InsertColumns,
+		"insert_rows":       &opts.InsertRows,
+		"insert_hyperlinks": &opts.InsertHyperlinks,
+		"delete_columns":    &opts.DeleteColumns,
+		"delete_rows":       &opts.DeleteRows,
+		"sort":              &opts.Sort,
+		"autofilter":        &opts.AutoFilter,
+		"pivot_tables":      &opts.PivotTables,
+		"edit_objects":      &opts.EditObjects,
+		"edit_scenarios":    &opts.EditScenarios,
+	}
+}
+
+// sheetPermissionNames lists the actions that can be allowed on a protected sheet
+func sheetPermissionNames() []string {
+	var names []string
+	for name := range sheetPermissions(&excelize.SheetProtectionOptions{}) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// protectionState describes the protection stored in a worksheet or workbook part
+type protectionState struct {
+	Protected   bool
+	HasPassword bool
+}
+
+// readSheetProtection reads the protection of a worksheet as stored in the file
+func readSheetProtection(f *excelize.File, sheet string) (protectionState, error) {
+	content, err := readWorksheetPart(f, sheet)
+	if err != nil {
+		return protectionState{}, err
+	}
+	var parsed struct {
+		Protection *struct {
+			Sheet     bool   `xml:"sheet,attr"`
+			Password  string `xml:"password,attr"`
+			HashValue string `xml:"hashValue,attr"`
+		} `xml:"sheetProtection"`
+	}
+	if err := xml.Unmarshal(content, &parsed); err != nil {
+		return protectionState{}, fmt.Errorf("failed to parse worksheet '%s': %w", sheet, err)
+	}
+	if parsed.Protection == nil || !parsed.Protection.Sheet {
+		return protectionState{}, nil
+	}
+	return protectionState{
+		Protected:   true,
+		HasPassword: parsed.Protection.Password != "" || parsed.Protection.HashValue != "",
+	}, nil
+}
+
+// readWorkbookProtection reads the protection of the workbook structure and windows as stored in the file
+func readWorkbookProtection(f *excelize.File) (protectionState, error) {
+	var parsed struct {
+		Protection *struct {
+			LockStructure bool   `xml:"lockStructure,attr"`
+			LockWindows   bool   `xml:"lockWindows,attr"`
+			Password      string `xml:"workbookPassword,attr"`
+			HashValue     string `xml:"workbookHashValue,attr"`
+		} `xml:"workbookProtection"`
+	}
+	if err := unmarshalPart(f, workbookPartPath(f), &parsed); err != nil {
+		return protectionState{}, err
+	}
+	if parsed.Protection == nil || !(parsed.Protection.LockStructure || parsed.Protection.LockWindows) {
+		return protectionState{}, nil
+	}
+	return protectionState{
+		Protected:   true,
+		HasPassword: parsed.Protection.Password != "" || parsed.Protection.HashValue != "",
+	}, nil
+}
+
+// unmarshalPart parses a package part as stored in the file
+func unmarshalPart(f *excelize.File, part string, v interface{}) error {
+	data, ok := f.Pkg.Load(part)
+	if !ok {
+		return fmt.Errorf("part %s not found in the package", part)
+	}
+	content, _ := data.([]byte)
+	if err := xml.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", part, err)
+	}
+	return nil
+}
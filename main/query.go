@@ -0,0 +1,538 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// dataTable is a header-based table of cell values read from a worksheet.
+// Numeric cells are held as float64, boolean cells as bool, empty cells as nil
+// and everything else as string.
+type dataTable struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	// RowNums holds the sheet row of each row of a table read by readTable
+	RowNums []int `json:"-"`
+}
+
+// queryFilter is a single predicate on a table column
+type queryFilter struct {
+	Column string      `json:"column"`
+	Op     string      `json:"op"`
+	Value  interface{} `json:"value"`
+}
+
+// queryAggregate computes an aggregate function over a column
+type queryAggregate struct {
+	Column   string `json:"column"`
+	Function string `json:"function"`
+	As       string `json:"as"`
+}
+
+// queryOrder sorts the result by a column
+type queryOrder struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+}
+
+// querySpec describes a filter/group/aggregate query over a dataTable.
+// Filters are combined with AND.
+type querySpec struct {
+	Select     []string         `json:"select"`
+	Filters    []queryFilter    `json:"filters"`
+	GroupBy    []string         `json:"group_by"`
+	Aggregates []queryAggregate `json:"aggregates"`
+	OrderBy    []queryOrder     `json:"order_by"`
+	Limit      int              `json:"limit"`
+}
+
+// parseRangeRef parses an A1-style range such as "A1:F100" into 1-based coordinates
+func parseRangeRef(ref string) (col1, row1, col2, row2 int, err error) {
+	parts := strings.Split(strings.ReplaceAll(ref, "$", ""), ":")
+	if len(parts) != 2 {
+		return 0, 0, 0, 0, fmt.Errorf("invalid range %q, expected format like 'A1:F100'", ref)
+	}
+	if col1, row1, err = excelize.CellNameToCoordinates(parts[0]); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if col2, row2, err = excelize.CellNameToCoordinates(parts[1]); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if col1 > col2 {
+		col1, col2 = col2, col1
+	}
+	if row1 > row2 {
+		row1, row2 = row2, row1
+	}
+	return col1, row1, col2, row2, nil
+}
+
+// splitSheetRef splits a reference such as 'My Sheet'!$A$1:$B$5 into its sheet
+// name and the range without '$' markers. The sheet is "" for unqualified references.
+func splitSheetRef(ref string) (sheet, cellRange string) {
+	ref = strings.TrimPrefix(strings.TrimSpace(ref), "=")
+	if idx := strings.LastIndex(ref, "!"); idx != -1 {
+		sheet = ref[:idx]
+		if len(sheet) >= 2 && strings.HasPrefix(sheet, "'") && strings.HasSuffix(sheet, "'") {
+			sheet = strings.ReplaceAll(sheet[1:len(sheet)-1], "''", "'")
+		}
+		ref = ref[idx+1:]
+	}
+	return sheet, strings.ReplaceAll(ref, "$", "")
+}
+
+// readTable reads a header-based table from a worksheet in a single streaming pass.
+// The first row of cellRange holds the column names. If cellRange is empty, the
+// table starts at A1 and spans every column used in the sheet.
+func readTable(f *excelize.File, sheet, cellRange string) (*dataTable, error) {
+	col1, row1, col2, row2 := 1, 1, 0, 0
+	if cellRange != "" {
+		var err error
+		if col1, row1, col2, row2, err = parseRangeRef(cellRange); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var raw [][]string
+	var rowNums []int
+	for rowNum := 1; rows.Next(); rowNum++ {
+		if rowNum < row1 {
+			continue
+		}
+		if row2 > 0 && rowNum > row2 {
+			break
+		}
+		cols, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, cols)
+		rowNums = append(rowNums, rowNum)
+	}
+	if err := rows.Error(); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("range contains no header row")
+	}
+
+	if col2 == 0 {
+		for _, cols := range raw {
+			if len(cols) > col2 {
+				col2 = len(cols)
+			}
+		}
+	}
+	width := col2 - col1 + 1
+	if width <= 0 {
+		return nil, errors.New("range contains no columns")
+	}
+
+	table := &dataTable{Columns: make([]string, width)}
+	for i := range table.Columns {
+		table.Columns[i] = cellAt(raw[0], col1+i)
+		if table.Columns[i] == "" {
+			name, _ := excelize.ColumnNumberToName(col1 + i)
+			table.Columns[i] = name
+		}
+	}
+	for n, cols := range raw[1:] {
+		row := make([]interface{}, width)
+		empty := true
+		for i := range row {
+			if row[i], err = cellValue(f, sheet, col1+i, rowNums[n+1], cellAt(cols, col1+i)); err != nil {
+				return nil, err
+			}
+			if row[i] != nil {
+				empty = false
+			}
+		}
+		if !empty {
+			table.Rows = append(table.Rows, row)
+			table.RowNums = append(table.RowNums, rowNums[n+1])
+		}
+	}
+	return table, nil
+}
+
+// cellAt returns the value of the 1-based column col, or "" if the row is shorter
+func cellAt(cols []string, col int) string {
+	if col-1 < len(cols) {
+		return cols[col-1]
+	}
+	return ""
+}
+
+// parseCellValue converts a raw cell string to float64, nil (empty) or string.
+// Text such as "NaN" or "Inf" that parses as a non-finite number stays a string.
+func parseCellValue(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	if num, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(num) && !math.IsInf(num, 0) {
+		return num
+	}
+	return value
+}
+
+// cellValue returns the typed value of a cell from its raw text: nil if it is
+// empty, a float64 for a number, a bool for a boolean and the text otherwise, so
+// that text such as "007" is never read as a number. Only text that could be a
+// number or a boolean needs the cell type to tell them apart.
+func cellValue(f *excelize.File, sheet string, col, row int, raw string) (interface{}, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	num, err := strconv.ParseFloat(raw, 64)
+	if err != nil && raw != "TRUE" && raw != "FALSE" {
+		return raw, nil
+	}
+	cell, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return nil, err
+	}
+	cellType, err := f.GetCellType(sheet, cell)
+	if err != nil {
+		return nil, err
+	}
+	switch cellType {
+	case excelize.CellTypeBool:
+		return raw == "1" || raw == "TRUE", nil
+	case excelize.CellTypeUnset, excelize.CellTypeNumber:
+		if !math.IsNaN(num) && !math.IsInf(num, 0) && raw != "TRUE" && raw != "FALSE" {
+			return num, nil
+		}
+	}
+	return raw, nil
+}
+
+// normalizeValue converts JSON arguments to the same representation as cellValue.
+// Strings stay strings, so a filter on "007" matches the text "007" but not the number 7.
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+	case int:
+		return float64(v)
+	}
+	return value
+}
+
+// compareValues orders two cell values: empty values first, then numbers, then
+// strings, then booleans with FALSE before TRUE
+func compareValues(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case float64:
+			return 1
+		case bool:
+			return 3
+		}
+		return 2
+	}
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch av := a.(type) {
+	case float64:
+		bv := b.(float64)
+		if av < bv {
+			return -1
+		} else if av > bv {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, fmt.Sprint(b))
+	case bool:
+		if bv := b.(bool); av != bv {
+			if av {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// columnIndex resolves a column name to its index, falling back to a case-insensitive match
+func (t *dataTable) columnIndex(name string) (int, error) {
+	for i, col := range t.Columns {
+		if col == name {
+			return i, nil
+		}
+	}
+	for i, col := range t.Columns {
+		if strings.EqualFold(col, name) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("unknown column %q", name)
+}
+
+// matchFilter reports whether value satisfies the filter operator against target
+func matchFilter(op string, value, target interface{}) (bool, error) {
+	switch strings.ToLower(op) {
+	case "=", "==", "eq":
+		return compareValues(value, normalizeValue(target)) == 0, nil
+	case "!=", "<>", "ne":
+		return compareValues(value, normalizeValue(target)) != 0, nil
+	case ">", "gt":
+		return value != nil && compareValues(value, normalizeValue(target)) > 0, nil
+	case ">=", "gte":
+		return value != nil && compareValues(value, normalizeValue(target)) >= 0, nil
+	case "<", "lt":
+		return value != nil && compareValues(value, normalizeValue(target)) < 0, nil
+	case "<=", "lte":
+		return value != nil && compareValues(value, normalizeValue(target)) <= 0, nil
+	case "contains":
+		return value != nil && strings.Contains(strings.ToLower(fmt.Sprint(value)), strings.ToLower(fmt.Sprint(target))), nil
+	case "in", "not_in":
+		items, ok := target.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("operator %q requires an array value", op)
+		}
+		found := false
+		for _, item := range items {
+			if compareValues(value, normalizeValue(item)) == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (strings.ToLower(op) == "in"), nil
+	case "is_empty":
+		return value == nil, nil
+	case "not_empty":
+		return value != nil, nil
+	}
+	return false, fmt.Errorf("unsupported filter operator %q", op)
+}
+
+// aggregateValues applies an aggregate function to a column of values
+func aggregateValues(function string, values []interface{}) (interface{}, error) {
+	switch strings.ToLower(function) {
+	case "count":
+		count := 0
+		for _, v := range values {
+			if v != nil {
+				count++
+			}
+		}
+		return float64(count), nil
+	case "count_distinct":
+		seen := make(map[string]bool)
+		for _, v := range values {
+			if v != nil {
+				seen[fmt.Sprint(v)] = true
+			}
+		}
+		return float64(len(seen)), nil
+	case "sum", "average", "avg":
+		sum, count := 0.0, 0
+		for _, v := range values {
+			if num, ok := v.(float64); ok {
+				sum += num
+				count++
+			}
+		}
+		if strings.ToLower(function) == "sum" {
+			return sum, nil
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		return sum / float64(count), nil
+	case "min", "max":
+		isMin := strings.ToLower(function) == "min"
+		var result interface{}
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			if result == nil {
+				result = v
+				continue
+			}
+			cmp := compareValues(v, result)
+			if (isMin && cmp < 0) || (!isMin && cmp > 0) {
+				result = v
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unsupported aggregate function %q", function)
+}
+
+// runQuery filters, groups, aggregates, orders and limits a table according to spec
+func runQuery(t *dataTable, spec *querySpec) (*dataTable, error) {
+	// Filter rows
+	filterIdx := make([]int, len(spec.Filters))
+	for i, filter := range spec.Filters {
+		idx, err := t.columnIndex(filter.Column)
+		if err != nil {
+			return nil, err
+		}
+		filterIdx[i] = idx
+	}
+	var rows [][]interface{}
+	for _, row := range t.Rows {
+		keep := true
+		for i, filter := range spec.Filters {
+			ok, err := matchFilter(filter.Op, row[filterIdx[i]], filter.Value)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			rows = append(rows, row)
+		}
+	}
+
+	result := &dataTable{}
+	if len(spec.GroupBy) > 0 || len(spec.Aggregates) > 0 {
+		groupIdx := make([]int, len(spec.GroupBy))
+		for i, col := range spec.GroupBy {
+			idx, err := t.columnIndex(col)
+			if err != nil {
+				return nil, err
+			}
+			groupIdx[i] = idx
+			result.Columns = append(result.Columns, t.Columns[idx])
+		}
+		aggIdx := make([]int, len(spec.Aggregates))
+		for i, agg := range spec.Aggregates {
+			fn := strings.ToLower(agg.Function)
+			if agg.Column == "*" && fn == "count" {
+				aggIdx[i] = -1
+			} else {
+				idx, err := t.columnIndex(agg.Column)
+				if err != nil {
+					return nil, err
+				}
+				aggIdx[i] = idx
+			}
+			name := agg.As
+			if name == "" {
+				name = fmt.Sprintf("%s(%s)", fn, agg.Column)
+			}
+			result.Columns = append(result.Columns, name)
+		}
+
+		// Group rows, preserving first-appearance order
+		var keys []string
+		groups := make(map[string][][]interface{})
+		for _, row := range rows {
+			parts := make([]string, len(groupIdx))
+			for i, idx := range groupIdx {
+				parts[i] = fmt.Sprintf("%T:%v", row[idx], row[idx])
+			}
+			key := strings.Join(parts, "\x00")
+			if _, exists := groups[key]; !exists {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], row)
+		}
+		if len(keys) == 0 && len(groupIdx) == 0 {
+			keys = append(keys, "")
+		}
+
+		for _, key := range keys {
+			members := groups[key]
+			out := make([]interface{}, 0, len(result.Columns))
+			for _, idx := range groupIdx {
+				out = append(out, members[0][idx])
+			}
+			for i, agg := range spec.Aggregates {
+				values := make([]interface{}, len(members))
+				for j, member := range members {
+					if aggIdx[i] == -1 {
+						values[j] = true
+					} else {
+						values[j] = member[aggIdx[i]]
+					}
+				}
+				value, err := aggregateValues(agg.Function, values)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, value)
+			}
+			result.Rows = append(result.Rows, out)
+		}
+	} else {
+		// Plain projection
+		selectIdx := make([]int, 0, len(t.Columns))
+		if len(spec.Select) == 0 {
+			for i := range t.Columns {
+				selectIdx = append(selectIdx, i)
+			}
+		} else {
+			for _, col := range spec.Select {
+				idx, err := t.columnIndex(col)
+				if err != nil {
+					return nil, err
+				}
+				selectIdx = append(selectIdx, idx)
+			}
+		}
+		for _, idx := range selectIdx {
+			result.Columns = append(result.Columns, t.Columns[idx])
+		}
+		for _, row := range rows {
+			out := make([]interface{}, len(selectIdx))
+			for i, idx := range selectIdx {
+				out[i] = row[idx]
+			}
+			result.Rows = append(result.Rows, out)
+		}
+	}
+
+	// Order and limit the result
+	if len(spec.OrderBy) > 0 {
+		orderIdx := make([]int, len(spec.OrderBy))
+		for i, order := range spec.OrderBy {
+			idx, err := result.columnIndex(order.Column)
+			if err != nil {
+				return nil, err
+			}
+			orderIdx[i] = idx
+		}
+		sort.SliceStable(result.Rows, func(a, b int) bool {
+			for i, order := range spec.OrderBy {
+				cmp := compareValues(result.Rows[a][orderIdx[i]], result.Rows[b][orderIdx[i]])
+				if cmp != 0 {
+					return (cmp < 0) != order.Desc
+				}
+			}
+			return false
+		})
+	}
+	if spec.Limit > 0 && len(result.Rows) > spec.Limit {
+		result.Rows = result.Rows[:spec.Limit]
+	}
+	if result.Rows == nil {
+		result.Rows = [][]interface{}{}
+	}
+	return result, nil
+}
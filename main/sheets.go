@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetStates maps the visibility names used by the tools to workbook sheet states
+var sheetStates = map[string]string{
+	"visible":     "",
+	"hidden":      "hidden",
+	"very_hidden": "veryHidden",
+}
+
+// sheetVisibility returns the visibility of a sheet as "visible", "hidden" or "very_hidden"
+func sheetVisibility(f *excelize.File, sheet string) string {
+	if index, _ := f.GetSheetIndex(sheet); index != -1 {
+		for visibility, state := range sheetStates {
+			if f.WorkBook.Sheets.Sheet[index].State == state {
+				return visibility
+			}
+		}
+	}
+	return "visible"
+}
+
+var hexColorPattern = regexp.MustCompile(`^[0-9A-Fa-f]{6}$`)
+
+// moveSheet moves a sheet to the given zero-based position in the tab order.
+// The workbook's sheet list is reordered directly because MoveSheet panics on
+// workbooks that contain chart sheets. Sheet-scoped defined names refer to
+// their sheet by position, so they are re-pointed at the same sheets.
+func moveSheet(f *excelize.File, sheet string, position int) error {
+	sheets := f.GetSheetList()
+	source, err := f.GetSheetIndex(sheet)
+	if err != nil {
+		return err
+	}
+	if source == -1 {
+		return fmt.Errorf("worksheet '%s' not found", sheet)
+	}
+	if position < 0 || position >= len(sheets) {
+		return fmt.Errorf("position %d is out of range 0-%d", position, len(sheets)-1)
+	}
+	if position == source {
+		return nil
+	}
+	activeSheet := f.GetSheetName(f.GetActiveSheetIndex())
+
+	moved := f.WorkBook.Sheets.Sheet[source]
+	f.WorkBook.Sheets.Sheet = slices.Insert(slices.Delete(f.WorkBook.Sheets.Sheet, source, source+1), position, moved)
+
+	if f.WorkBook.DefinedNames != nil {
+		for _, dn := range f.WorkBook.DefinedNames.DefinedName {
+			if dn.LocalSheetID != nil && *dn.LocalSheetID < len(sheets) {
+				*dn.LocalSheetID, _ = f.GetSheetIndex(sheets[*dn.LocalSheetID])
+			}
+		}
+	}
+	activeIndex, _ := f.GetSheetIndex(activeSheet)
+	activateSheet(f, activeIndex)
+	return nil
+}
+
+// activateSheet makes the sheet at index the active, selected tab.
+// SetActiveSheet stops updating tab selection at the first chart sheet, so it
+// is first applied with the worksheets ordered ahead of all other sheets.
+func activateSheet(f *excelize.File, index int) {
+	sheets := f.GetSheetList()
+	original := f.WorkBook.Sheets.Sheet
+	worksheets := make(map[string]bool)
+	for _, name := range sheets {
+		worksheets[name] = isWorksheet(f, name)
+	}
+	reordered := slices.Clone(original)
+	sort.SliceStable(reordered, func(i, j int) bool {
+		return worksheets[reordered[i].Name] && !worksheets[reordered[j].Name]
+	})
+	f.WorkBook.Sheets.Sheet = reordered
+	if reorderedIndex, _ := f.GetSheetIndex(sheets[index]); reorderedIndex != -1 {
+		f.SetActiveSheet(reorderedIndex)
+	}
+	f.WorkBook.Sheets.Sheet = original
+	f.SetActiveSheet(index)
+}
+
+// setSheetVisibility sets a sheet to "visible", "hidden" or "very_hidden".
+// Hiding the active sheet activates the next visible one, and the last visible
+// sheet cannot be hidden. The state is set on the workbook directly because
+// SetSheetVisible fails on workbooks that contain chart sheets.
+func setSheetVisibility(f *excelize.File, sheet, visibility string) error {
+	state, ok := sheetStates[visibility]
+	if !ok {
+		return fmt.Errorf("invalid visibility '%s', must be one of: visible, hidden, very_hidden", visibility)
+	}
+	index, err := f.GetSheetIndex(sheet)
+	if err != nil {
+		return err
+	}
+	if index == -1 {
+		return fmt.Errorf("worksheet '%s' not found", sheet)
+	}
+	sheets := f.WorkBook.Sheets.Sheet
+	if state != "" {
+		nextVisible := -1
+		for i := range sheets {
+			candidate := (index + 1 + i) % len(sheets)
+			if candidate != index && sheets[candidate].State == "" {
+				nextVisible = candidate
+				break
+			}
+		}
+		if nextVisible == -1 {
+			return errors.New("a workbook must contain at least one visible sheet")
+		}
+		if f.GetActiveSheetIndex() == index {
+			activateSheet(f, nextVisible)
+		}
+	}
+	sheets[index].State = state
+	return nil
+}
+
+// setTabColor sets the tab color of a worksheet from a 6-digit hex RGB value
+func setTabColor(f *excelize.File, sheet, color string) error {
+	color = strings.TrimPrefix(color, "#")
+	if !hexColorPattern.MatchString(color) {
+		return fmt.Errorf("invalid color '%s', must be a 6-digit hex RGB value such as 'FF0000'", color)
+	}
+	argb := "FF" + strings.ToUpper(color)
+	return f.SetSheetProps(sheet, &excelize.SheetPropsOptions{TabColorRGB: &argb})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// tablePart holds the rows of a table that share one key value, with the sheet
+// row of each
+type tablePart struct {
+	Key     string
+	Rows    [][]interface{}
+	RowNums []int
+}
+
+// invalidSheetNameChars are not allowed in sheet names; invalidFileNameChars
+// are replaced in generated file names
+var (
+	invalidSheetNameChars = strings.NewReplacer(":", "_", `\`, "_", "/", "_", "?", "_", "*", "_", "[", "_", "]", "_")
+	invalidFileNameChars  = strings.NewReplacer("/", "_", `\`, "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_")
+)
+
+// keyLabel formats a key value for use in sheet and file names
+func keyLabel(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "(blank)"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// splitTableByKey groups the rows of a table by the values of a key column,
+// in order of first appearance
+func splitTableByKey(table *dataTable, keyColumn string) ([]tablePart, error) {
+	keyIndex, err := table.columnIndex(keyColumn)
+	if err != nil {
+		return nil, err
+	}
+	// Empty keys are grouped apart from a literal "(blank)" key
+	type groupKey struct {
+		blank bool
+		label string
+	}
+	var parts []tablePart
+	index := make(map[groupKey]int)
+	for n, row := range table.Rows {
+		key := groupKey{blank: row[keyIndex] == nil, label: keyLabel(row[keyIndex])}
+		i, ok := index[key]
+		if !ok {
+			i = len(parts)
+			index[key] = i
+			parts = append(parts, tablePart{Key: key.label})
+		}
+		parts[i].Rows = append(parts[i].Rows, row)
+		parts[i].RowNums = append(parts[i].RowNums, table.RowNums[n])
+	}
+	return parts, nil
+}
+
+// safeSheetName turns a key into a valid sheet name that is unique in f
+func safeSheetName(f *excelize.File, key string) string {
+	name := strings.Trim(invalidSheetNameChars.Replace(key), "'")
+	if runes := []rune(name); len(runes) > excelize.MaxSheetNameLength {
+		name = string(runes[:excelize.MaxSheetNameLength])
+	}
+	if name == "" {
+		name = "(blank)"
+	}
+	return uniqueSheetName(f, name)
+}
+
+// writeTablePart writes a header row and the rows of one part to a new sheet,
+// reproducing the header formatting, data column styles and column widths. The
+// rows are copied from the table's sheet with their types, formulas and styles.
+func writeTablePart(f *excelize.File, sheet string, columns []string, part tablePart, format *tableFormat) error {
+	if index, _ := f.GetSheetIndex(sheet); index == -1 {
+		if _, err := f.NewSheet(sheet); err != nil {
+			return err
+		}
+	}
+	if err := format.applyColumns(f, sheet, 1); err != nil {
+		return err
+	}
+	header := toInterfaceSlice(columns)
+	if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return err
+	}
+	if err := format.applyHeader(f, sheet, 1); err != nil {
+		return err
+	}
+	order := make([]int, len(columns))
+	for i := range order {
+		order[i] = i
+	}
+	for i, rowNum := range part.RowNums {
+		if err := copyTableRow(format.styles(f), format.sheet, format.col, rowNum, sheet, 1, i+2, order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitFilePaths names the workbook for each part: the source file name followed by
+// the key, in dir or, if dir is empty, next to the source file. Keys that map to the
+// same file name, such as "A/B" and "A_B", or that differ only in case, get a
+// counter suffix so that no part overwrites another.
+func splitFilePaths(source, dir string, parts []tablePart) []string {
+	if dir == "" {
+		dir = filepath.Dir(source)
+	}
+	base := strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	paths := make([]string, len(parts))
+	seen := make(map[string]bool)
+	for i, part := range parts {
+		name := base + "_" + invalidFileNameChars.Replace(part.Key)
+		unique := name
+		for n := 2; seen[strings.ToLower(unique)]; n++ {
+			unique = fmt.Sprintf("%s_%d", name, n)
+		}
+		seen[strings.ToLower(unique)] = true
+		paths[i] = filepath.Join(dir, unique+".xlsx")
+	}
+	return paths
+}
@@ -0,0 +1,1071 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// This file implements a constrained SQL dialect over worksheet ranges:
+//
+//	SELECT [DISTINCT] items FROM source [[AS] alias]
+//	  [[INNER | LEFT [OUTER]] JOIN source [[AS] alias] ON col = col ...]
+//	  [WHERE condition] [GROUP BY cols] [ORDER BY cols [ASC|DESC]] [LIMIT n]
+//
+// A source is a sheet name with an optional range, e.g. 'Sales'!A1:F5000 or Sales.
+// The first row of each source holds the column names.
+
+const (
+	sqlTokEOF = iota
+	sqlTokIdent
+	sqlTokString
+	sqlTokNumber
+	sqlTokSymbol
+)
+
+type sqlToken struct {
+	kind   int
+	text   string
+	quoted bool // identifier was quoted and must not be treated as a keyword
+}
+
+// sqlKeywords cannot be used as unquoted table aliases
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "DISTINCT": true, "FROM": true, "WHERE": true, "GROUP": true,
+	"BY": true, "ORDER": true, "LIMIT": true, "JOIN": true, "INNER": true,
+	"LEFT": true, "OUTER": true, "ON": true, "AS": true, "AND": true, "OR": true,
+	"NOT": true, "IN": true, "LIKE": true, "IS": true, "NULL": true, "ASC": true,
+	"DESC": true, "BETWEEN": true, "TRUE": true, "FALSE": true,
+}
+
+// sqlAggregates maps SQL aggregate names to aggregateValues functions
+var sqlAggregates = map[string]string{
+	"SUM":     "sum",
+	"COUNT":   "count",
+	"AVG":     "average",
+	"AVERAGE": "average",
+	"MIN":     "min",
+	"MAX":     "max",
+}
+
+// tokenizeSQL splits a query into tokens
+func tokenizeSQL(query string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '\'':
+			// String literal, '' escapes a quote
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, errors.New("unterminated string literal")
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokString, text: sb.String()})
+		case ch == '"' || ch == '`' || ch == '[':
+			// Quoted identifier
+			closing := ch
+			if ch == '[' {
+				closing = ']'
+			}
+			end := i + 1
+			for end < len(runes) && runes[end] != closing {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, errors.New("unterminated quoted identifier")
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokIdent, text: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+		case unicode.IsDigit(ch) || (ch == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokNumber, text: string(runes[start:i])})
+		case unicode.IsLetter(ch) || ch == '_' || ch == '$':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) ||
+				runes[i] == '_' || runes[i] == '$' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokIdent, text: string(runes[start:i])})
+		default:
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				if two == "<=" || two == ">=" || two == "<>" || two == "!=" {
+					tokens = append(tokens, sqlToken{kind: sqlTokSymbol, text: two})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("(),*!:=<>;-", ch) {
+				return nil, fmt.Errorf("unexpected character %q", ch)
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokSymbol, text: string(ch)})
+			i++
+		}
+	}
+	return append(tokens, sqlToken{kind: sqlTokEOF}), nil
+}
+
+// sqlSource is a table read from a sheet range
+type sqlSource struct {
+	Sheet string
+	Range string
+	Alias string
+}
+
+// sqlJoin joins a source on an equality between two columns
+type sqlJoin struct {
+	Source sqlSource
+	Left   bool
+	OnA    string
+	OnB    string
+}
+
+// sqlSelectItem is a column, an aggregate call or '*'
+type sqlSelectItem struct {
+	Star      bool
+	Column    string
+	Aggregate string // aggregateValues function name, empty for plain columns
+	Alias     string
+}
+
+// sqlOrder sorts by an output column, a source column or a 1-based position
+type sqlOrder struct {
+	Column string
+	Desc   bool
+}
+
+// sqlQuery is a parsed SELECT statement
+type sqlQuery struct {
+	Distinct bool
+	Items    []sqlSelectItem
+	From     sqlSource
+	Joins    []sqlJoin
+	Where    sqlExpr
+	GroupBy  []string
+	OrderBy  []sqlOrder
+	Limit    int // -1 without a LIMIT clause
+}
+
+// sqlExpr is a node of a WHERE condition
+type sqlExpr interface{}
+
+type sqlLogical struct {
+	Op          string // AND, OR
+	Left, Right sqlExpr
+}
+
+type sqlNot struct {
+	Expr sqlExpr
+}
+
+type sqlCompare struct {
+	Op          string
+	Left, Right sqlExpr
+}
+
+type sqlIn struct {
+	Operand sqlExpr
+	List    []sqlExpr
+	Not     bool
+}
+
+type sqlLike struct {
+	Operand sqlExpr
+	Pattern *regexp.Regexp
+	Not     bool
+}
+
+type sqlIsNull struct {
+	Operand sqlExpr
+	Not     bool
+}
+
+type sqlBetween struct {
+	Operand, Low, High sqlExpr
+	Not                bool
+}
+
+type sqlColumnRef struct {
+	Name string
+}
+
+type sqlLiteral struct {
+	Value interface{}
+}
+
+// sqlParser is a recursive-descent parser over tokens
+type sqlParser struct {
+	tokens []sqlToken
+	pos    int
+}
+
+func (p *sqlParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sqlParser) next() sqlToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != sqlTokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isKeyword reports whether the current token is the unquoted keyword kw
+func (p *sqlParser) isKeyword(kw string) bool {
+	tok := p.peek()
+	return tok.kind == sqlTokIdent && !tok.quoted && strings.EqualFold(tok.text, kw)
+}
+
+func (p *sqlParser) acceptKeyword(kw string) bool {
+	if p.isKeyword(kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sqlParser) expectKeyword(kw string) error {
+	if !p.acceptKeyword(kw) {
+		return fmt.Errorf("expected %s near %q", kw, p.peek().text)
+	}
+	return nil
+}
+
+func (p *sqlParser) isSymbol(sym string) bool {
+	tok := p.peek()
+	return tok.kind == sqlTokSymbol && tok.text == sym
+}
+
+func (p *sqlParser) expectSymbol(sym string) error {
+	if !p.isSymbol(sym) {
+		return fmt.Errorf("expected '%s' near %q", sym, p.peek().text)
+	}
+	p.pos++
+	return nil
+}
+
+// parseSQL parses a SELECT statement
+func parseSQL(query string) (*sqlQuery, error) {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{tokens: tokens}
+	q := &sqlQuery{Limit: -1}
+
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	q.Distinct = p.acceptKeyword("DISTINCT")
+	for {
+		item, err := p.parseSelectItem()
+		if err != nil {
+			return nil, err
+		}
+		q.Items = append(q.Items, item)
+		if !p.isSymbol(",") {
+			break
+		}
+		p.next()
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	if q.From, err = p.parseSource(); err != nil {
+		return nil, err
+	}
+	for {
+		left := false
+		if p.acceptKeyword("LEFT") {
+			left = true
+			p.acceptKeyword("OUTER")
+		} else {
+			p.acceptKeyword("INNER")
+		}
+		if !p.acceptKeyword("JOIN") {
+			if left {
+				return nil, fmt.Errorf("expected JOIN near %q", p.peek().text)
+			}
+			break
+		}
+		join := sqlJoin{Left: left}
+		if join.Source, err = p.parseSource(); err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("ON"); err != nil {
+			return nil, err
+		}
+		if join.OnA, err = p.parseColumnName(); err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol("="); err != nil {
+			return nil, err
+		}
+		if join.OnB, err = p.parseColumnName(); err != nil {
+			return nil, err
+		}
+		q.Joins = append(q.Joins, join)
+	}
+
+	if p.acceptKeyword("WHERE") {
+		if q.Where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			col, err := p.parseColumnName()
+			if err != nil {
+				return nil, err
+			}
+			q.GroupBy = append(q.GroupBy, col)
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			var order sqlOrder
+			if p.peek().kind == sqlTokNumber {
+				order.Column = p.next().text
+			} else if order.Column, err = p.parseColumnName(); err != nil {
+				return nil, err
+			}
+			if p.acceptKeyword("DESC") {
+				order.Desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			q.OrderBy = append(q.OrderBy, order)
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		tok := p.next()
+		limit, err := strconv.Atoi(tok.text)
+		if tok.kind != sqlTokNumber || err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid LIMIT %q", tok.text)
+		}
+		q.Limit = limit
+	}
+	if p.isSymbol(";") {
+		p.next()
+	}
+	if tok := p.peek(); tok.kind != sqlTokEOF {
+		return nil, fmt.Errorf("unexpected %q", tok.text)
+	}
+	return q, nil
+}
+
+// parseColumnName parses a possibly qualified column name such as region, s.region or s.[Unit Price]
+func (p *sqlParser) parseColumnName() (string, error) {
+	tok := p.next()
+	if tok.kind != sqlTokIdent {
+		return "", fmt.Errorf("expected column name near %q", tok.text)
+	}
+	name := tok.text
+	if !tok.quoted && strings.HasSuffix(name, ".") && p.peek().kind == sqlTokIdent {
+		name += p.next().text
+	}
+	return name, nil
+}
+
+// parseSelectItem parses '*', an aggregate call or a column, with an optional alias
+func (p *sqlParser) parseSelectItem() (sqlSelectItem, error) {
+	var item sqlSelectItem
+	if p.isSymbol("*") {
+		p.next()
+		item.Star = true
+		return item, nil
+	}
+	tok := p.peek()
+	if fn, ok := sqlAggregates[strings.ToUpper(tok.text)]; ok && tok.kind == sqlTokIdent && !tok.quoted &&
+		p.tokens[p.pos+1].kind == sqlTokSymbol && p.tokens[p.pos+1].text == "(" {
+		p.pos += 2
+		item.Aggregate = fn
+		if p.acceptKeyword("DISTINCT") {
+			if fn != "count" {
+				return item, errors.New("DISTINCT is only supported in COUNT")
+			}
+			item.Aggregate = "count_distinct"
+		}
+		if p.isSymbol("*") {
+			if fn != "count" {
+				return item, fmt.Errorf("%s(*) is not supported", strings.ToUpper(tok.text))
+			}
+			p.next()
+			item.Column = "*"
+		} else {
+			col, err := p.parseColumnName()
+			if err != nil {
+				return item, err
+			}
+			item.Column = col
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return item, err
+		}
+	} else {
+		col, err := p.parseColumnName()
+		if err != nil {
+			return item, err
+		}
+		item.Column = col
+	}
+	if p.acceptKeyword("AS") {
+		tok := p.next()
+		if tok.kind != sqlTokIdent && tok.kind != sqlTokString {
+			return item, fmt.Errorf("expected alias near %q", tok.text)
+		}
+		item.Alias = tok.text
+	} else if tok := p.peek(); tok.kind == sqlTokIdent && (tok.quoted || !sqlKeywords[strings.ToUpper(tok.text)]) {
+		item.Alias = p.next().text
+	}
+	return item, nil
+}
+
+// parseSource parses 'Sheet'!A1:F100, Sheet!A1:F100 or Sheet with an optional alias
+func (p *sqlParser) parseSource() (sqlSource, error) {
+	var src sqlSource
+	tok := p.next()
+	if tok.kind != sqlTokIdent && tok.kind != sqlTokString {
+		return src, fmt.Errorf("expected sheet name near %q", tok.text)
+	}
+	src.Sheet = tok.text
+	if p.isSymbol("!") {
+		p.next()
+		start := p.next()
+		if start.kind != sqlTokIdent {
+			return src, fmt.Errorf("expected range after '%s!'", src.Sheet)
+		}
+		if err := p.expectSymbol(":"); err != nil {
+			return src, err
+		}
+		end := p.next()
+		if end.kind != sqlTokIdent {
+			return src, fmt.Errorf("expected range after '%s!'", src.Sheet)
+		}
+		src.Range = start.text + ":" + end.text
+	}
+	src.Alias = src.Sheet
+	if p.acceptKeyword("AS") {
+		tok := p.next()
+		if tok.kind != sqlTokIdent {
+			return src, fmt.Errorf("expected alias near %q", tok.text)
+		}
+		src.Alias = tok.text
+	} else if tok := p.peek(); tok.kind == sqlTokIdent && (tok.quoted || !sqlKeywords[strings.ToUpper(tok.text)]) {
+		src.Alias = p.next().text
+	}
+	return src, nil
+}
+
+func (p *sqlParser) parseOr() (sqlExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlLogical{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAnd() (sqlExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlLogical{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseNot() (sqlExpr, error) {
+	if p.acceptKeyword("NOT") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlNot{Expr: expr}, nil
+	}
+	return p.parsePredicate()
+}
+
+// parsePredicate parses a parenthesized condition or a comparison, IN, LIKE, IS NULL or BETWEEN test
+func (p *sqlParser) parsePredicate() (sqlExpr, error) {
+	if p.isSymbol("(") {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expectSymbol(")")
+	}
+	operand, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == sqlTokSymbol {
+		switch tok.text {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+			p.next()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &sqlCompare{Op: tok.text, Left: operand, Right: right}, nil
+		}
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return &sqlIsNull{Operand: operand, Not: not}, nil
+	}
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("IN"):
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		in := &sqlIn{Operand: operand, Not: not}
+		for {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			in.List = append(in.List, item)
+			if !p.isSymbol(",") {
+				break
+			}
+			p.next()
+		}
+		return in, p.expectSymbol(")")
+	case p.acceptKeyword("LIKE"):
+		tok := p.next()
+		if tok.kind != sqlTokString {
+			return nil, errors.New("LIKE requires a string pattern")
+		}
+		pattern := regexp.QuoteMeta(tok.text)
+		pattern = strings.ReplaceAll(pattern, "%", ".*")
+		pattern = strings.ReplaceAll(pattern, "_", ".")
+		re, err := regexp.Compile("(?is)^" + pattern + "$")
+		if err != nil {
+			return nil, err
+		}
+		return &sqlLike{Operand: operand, Pattern: re, Not: not}, nil
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlBetween{Operand: operand, Low: low, High: high, Not: not}, nil
+	}
+	return nil, fmt.Errorf("expected comparison near %q", p.peek().text)
+}
+
+// parseOperand parses a column reference or a literal
+func (p *sqlParser) parseOperand() (sqlExpr, error) {
+	negative := false
+	if p.isSymbol("-") {
+		p.next()
+		negative = true
+	}
+	tok := p.peek()
+	switch {
+	case tok.kind == sqlTokNumber:
+		p.next()
+		num, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		if negative {
+			num = -num
+		}
+		return &sqlLiteral{Value: num}, nil
+	case negative:
+		return nil, fmt.Errorf("expected number after '-' near %q", tok.text)
+	case tok.kind == sqlTokString:
+		p.next()
+		return &sqlLiteral{Value: normalizeValue(tok.text)}, nil
+	case p.isKeyword("NULL"):
+		p.next()
+		return &sqlLiteral{Value: nil}, nil
+	case p.isKeyword("TRUE"), p.isKeyword("FALSE"):
+		p.next()
+		return &sqlLiteral{Value: strings.EqualFold(tok.text, "TRUE")}, nil
+	case tok.kind == sqlTokIdent:
+		name, err := p.parseColumnName()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlColumnRef{Name: name}, nil
+	}
+	return nil, fmt.Errorf("expected value near %q", tok.text)
+}
+
+// sqlRelation is the row set produced by FROM and JOIN clauses.
+// Each column remembers the alias of the source it came from.
+type sqlRelation struct {
+	Tables  []string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// resolve finds a column by "alias.column" or by an unambiguous bare column name
+func (r *sqlRelation) resolve(name string) (int, error) {
+	for i := range r.Columns {
+		if strings.EqualFold(r.Tables[i]+"."+r.Columns[i], name) {
+			return i, nil
+		}
+	}
+	found := -1
+	for i, col := range r.Columns {
+		if strings.EqualFold(col, name) {
+			if found != -1 {
+				return -1, fmt.Errorf("ambiguous column %q, qualify it with a table alias", name)
+			}
+			found = i
+		}
+	}
+	if found == -1 {
+		return -1, fmt.Errorf("unknown column %q", name)
+	}
+	return found, nil
+}
+
+// loadRelation reads a source into a relation
+func loadRelation(f *excelize.File, src sqlSource) (*sqlRelation, error) {
+	table, err := readTable(f, src.Sheet, src.Range)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", src.Sheet, err)
+	}
+	rel := &sqlRelation{Columns: table.Columns, Rows: table.Rows}
+	for range table.Columns {
+		rel.Tables = append(rel.Tables, src.Alias)
+	}
+	return rel, nil
+}
+
+// joinRelations hash-joins right onto left on the columns named in join
+func joinRelations(left, right *sqlRelation, join sqlJoin) (*sqlRelation, error) {
+	combined := &sqlRelation{
+		Tables:  append(append([]string{}, left.Tables...), right.Tables...),
+		Columns: append(append([]string{}, left.Columns...), right.Columns...),
+	}
+	a, err := combined.resolve(join.OnA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := combined.resolve(join.OnB)
+	if err != nil {
+		return nil, err
+	}
+	// Make a the left-side key and b the right-side key
+	if a >= len(left.Columns) {
+		a, b = b, a
+	}
+	if a >= len(left.Columns) || b < len(left.Columns) {
+		return nil, fmt.Errorf("join condition must compare a column of '%s' with a column of the joined table", join.Source.Alias)
+	}
+	b -= len(left.Columns)
+
+	index := make(map[string][][]interface{})
+	for _, row := range right.Rows {
+		if row[b] == nil {
+			continue
+		}
+		key := fmt.Sprint(row[b])
+		index[key] = append(index[key], row)
+	}
+	for _, row := range left.Rows {
+		var matches [][]interface{}
+		if row[a] != nil {
+			matches = index[fmt.Sprint(row[a])]
+		}
+		if len(matches) == 0 && join.Left {
+			matches = [][]interface{}{make([]interface{}, len(right.Columns))}
+		}
+		for _, match := range matches {
+			out := make([]interface{}, 0, len(combined.Columns))
+			out = append(out, row...)
+			out = append(out, match...)
+			combined.Rows = append(combined.Rows, out)
+		}
+	}
+	return combined, nil
+}
+
+// evalOperand returns the value of a column reference or literal for a row
+func evalOperand(rel *sqlRelation, expr sqlExpr, row []interface{}) (interface{}, error) {
+	switch e := expr.(type) {
+	case *sqlLiteral:
+		return e.Value, nil
+	case *sqlColumnRef:
+		idx, err := rel.resolve(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		return row[idx], nil
+	}
+	return nil, errors.New("invalid operand")
+}
+
+// evalCondition evaluates a WHERE condition for a row. Comparisons involving NULL are false.
+func evalCondition(rel *sqlRelation, expr sqlExpr, row []interface{}) (bool, error) {
+	switch e := expr.(type) {
+	case *sqlLogical:
+		left, err := evalCondition(rel, e.Left, row)
+		if err != nil {
+			return false, err
+		}
+		if e.Op == "AND" && !left {
+			return false, nil
+		}
+		if e.Op == "OR" && left {
+			return true, nil
+		}
+		return evalCondition(rel, e.Right, row)
+	case *sqlNot:
+		result, err := evalCondition(rel, e.Expr, row)
+		return !result, err
+	case *sqlCompare:
+		left, err := evalOperand(rel, e.Left, row)
+		if err != nil {
+			return false, err
+		}
+		right, err := evalOperand(rel, e.Right, row)
+		if err != nil {
+			return false, err
+		}
+		if left == nil || right == nil {
+			return false, nil
+		}
+		return matchFilter(e.Op, left, right)
+	case *sqlIn:
+		value, err := evalOperand(rel, e.Operand, row)
+		if err != nil || value == nil {
+			return false, err
+		}
+		for _, item := range e.List {
+			candidate, err := evalOperand(rel, item, row)
+			if err != nil {
+				return false, err
+			}
+			if compareValues(value, candidate) == 0 {
+				return !e.Not, nil
+			}
+		}
+		return e.Not, nil
+	case *sqlLike:
+		value, err := evalOperand(rel, e.Operand, row)
+		if err != nil || value == nil {
+			return false, err
+		}
+		return e.Pattern.MatchString(fmt.Sprint(value)) != e.Not, nil
+	case *sqlIsNull:
+		value, err := evalOperand(rel, e.Operand, row)
+		if err != nil {
+			return false, err
+		}
+		return (value == nil) != e.Not, nil
+	case *sqlBetween:
+		value, err := evalOperand(rel, e.Operand, row)
+		if err != nil {
+			return false, err
+		}
+		low, err := evalOperand(rel, e.Low, row)
+		if err != nil {
+			return false, err
+		}
+		high, err := evalOperand(rel, e.High, row)
+		if err != nil {
+			return false, err
+		}
+		if value == nil || low == nil || high == nil {
+			return false, nil
+		}
+		inside := compareValues(value, low) >= 0 && compareValues(value, high) <= 0
+		return inside != e.Not, nil
+	}
+	return false, errors.New("invalid condition")
+}
+
+// runSQL executes a parsed query against the workbook
+func runSQL(f *excelize.File, q *sqlQuery) (*dataTable, error) {
+	rel, err := loadRelation(f, q.From)
+	if err != nil {
+		return nil, err
+	}
+	for _, join := range q.Joins {
+		right, err := loadRelation(f, join.Source)
+		if err != nil {
+			return nil, err
+		}
+		if rel, err = joinRelations(rel, right, join); err != nil {
+			return nil, err
+		}
+	}
+
+	// WHERE
+	var rows [][]interface{}
+	for _, row := range rel.Rows {
+		if q.Where != nil {
+			ok, err := evalCondition(rel, q.Where, row)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	// Resolve select items into output columns
+	type outputColumn struct {
+		name      string
+		index     int // source column, -1 for COUNT(*)
+		aggregate string
+	}
+	var outputs []outputColumn
+	grouped := len(q.GroupBy) > 0
+	for _, item := range q.Items {
+		if item.Star {
+			for i, col := range rel.Columns {
+				name := col
+				if len(q.Joins) > 0 {
+					name = rel.Tables[i] + "." + col
+				}
+				outputs = append(outputs, outputColumn{name: name, index: i})
+			}
+			continue
+		}
+		out := outputColumn{name: item.Alias, aggregate: item.Aggregate, index: -1}
+		if item.Column != "*" {
+			if out.index, err = rel.resolve(item.Column); err != nil {
+				return nil, err
+			}
+		}
+		if out.name == "" {
+			if item.Aggregate != "" {
+				out.name = fmt.Sprintf("%s(%s)", item.Aggregate, item.Column)
+			} else {
+				out.name = rel.Columns[out.index]
+			}
+		}
+		if item.Aggregate != "" {
+			grouped = true
+		}
+		outputs = append(outputs, out)
+	}
+
+	// Each output row keeps the source row it came from so ORDER BY can use source columns
+	type resultRow struct {
+		values []interface{}
+		source []interface{}
+	}
+	var results []resultRow
+	if grouped {
+		groupIdx := make([]int, len(q.GroupBy))
+		for i, col := range q.GroupBy {
+			if groupIdx[i], err = rel.resolve(col); err != nil {
+				return nil, err
+			}
+		}
+		for _, out := range outputs {
+			if out.aggregate != "" {
+				continue
+			}
+			inGroup := false
+			for _, idx := range groupIdx {
+				if idx == out.index {
+					inGroup = true
+				}
+			}
+			if !inGroup {
+				return nil, fmt.Errorf("column %q must appear in GROUP BY or be used in an aggregate", out.name)
+			}
+		}
+
+		var keys []string
+		groups := make(map[string][][]interface{})
+		for _, row := range rows {
+			parts := make([]string, len(groupIdx))
+			for i, idx := range groupIdx {
+				parts[i] = fmt.Sprintf("%T:%v", row[idx], row[idx])
+			}
+			key := strings.Join(parts, "\x00")
+			if _, exists := groups[key]; !exists {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], row)
+		}
+		if len(keys) == 0 && len(groupIdx) == 0 {
+			keys = append(keys, "")
+		}
+		for _, key := range keys {
+			members := groups[key]
+			source := make([]interface{}, len(rel.Columns))
+			if len(members) > 0 {
+				source = members[0]
+			}
+			values := make([]interface{}, len(outputs))
+			for i, out := range outputs {
+				if out.aggregate == "" {
+					values[i] = source[out.index]
+					continue
+				}
+				column := make([]interface{}, len(members))
+				for j, member := range members {
+					if out.index == -1 {
+						column[j] = true
+					} else {
+						column[j] = member[out.index]
+					}
+				}
+				if values[i], err = aggregateValues(out.aggregate, column); err != nil {
+					return nil, err
+				}
+			}
+			results = append(results, resultRow{values: values, source: source})
+		}
+	} else {
+		for _, row := range rows {
+			values := make([]interface{}, len(outputs))
+			for i, out := range outputs {
+				values[i] = row[out.index]
+			}
+			results = append(results, resultRow{values: values, source: row})
+		}
+	}
+
+	// DISTINCT
+	if q.Distinct {
+		seen := make(map[string]bool)
+		var unique []resultRow
+		for _, row := range results {
+			key := fmt.Sprintf("%#v", row.values)
+			if !seen[key] {
+				seen[key] = true
+				unique = append(unique, row)
+			}
+		}
+		results = unique
+	}
+
+	// ORDER BY output columns, positions or source columns
+	if len(q.OrderBy) > 0 {
+		type sortKey struct {
+			index  int
+			source bool
+		}
+		keys := make([]sortKey, len(q.OrderBy))
+		for i, order := range q.OrderBy {
+			if pos, err := strconv.Atoi(order.Column); err == nil {
+				if pos < 1 || pos > len(outputs) {
+					return nil, fmt.Errorf("ORDER BY position %d is out of range", pos)
+				}
+				keys[i] = sortKey{index: pos - 1}
+				continue
+			}
+			found := false
+			for j, out := range outputs {
+				if strings.EqualFold(out.name, order.Column) {
+					keys[i] = sortKey{index: j}
+					found = true
+					break
+				}
+			}
+			if !found {
+				idx, err := rel.resolve(order.Column)
+				if err != nil {
+					return nil, err
+				}
+				keys[i] = sortKey{index: idx, source: true}
+			}
+		}
+		sort.SliceStable(results, func(a, b int) bool {
+			for i, key := range keys {
+				va, vb := results[a].values, results[b].values
+				if key.source {
+					va, vb = results[a].source, results[b].source
+				}
+				cmp := compareValues(va[key.index], vb[key.index])
+				if cmp != 0 {
+					return (cmp < 0) != q.OrderBy[i].Desc
+				}
+			}
+			return false
+		})
+	}
+	if q.Limit >= 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+
+	table := &dataTable{Rows: [][]interface{}{}}
+	for _, out := range outputs {
+		table.Columns = append(table.Columns, out.name)
+	}
+	for _, row := range results {
+		table.Rows = append(table.Rows, row.values)
+	}
+	return table, nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newSQLTestFile returns a workbook with a Sales table and a Regions lookup table
+func newSQLTestFile(t *testing.T) *excelize.File {
+	t.Helper()
+	f := excelize.NewFile()
+	t.Cleanup(func() { f.Close() })
+	if err := f.SetSheetName("Sheet1", "Sales"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.NewSheet("Regions"); err != nil {
+		t.Fatal(err)
+	}
+	sheets := map[string][][]interface{}{
+		"Sales": {
+			{"Id", "Region", "Amount", "Paid"},
+			{"007", "N", 100, true},
+			{7, "S", 50, false},
+			{"A1", "N", 25, false},
+			{"B2", "W", 75, true},
+		},
+		"Regions": {
+			{"Code", "Manager"},
+			{"N", "Ana"},
+			{"S", "Ben"},
+		},
+	}
+	for sheet, rows := range sheets {
+		for i, row := range rows {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return f
+}
+
+func TestParseSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  *sqlQuery
+	}{
+		{
+			name:  "star from sheet",
+			query: "SELECT * FROM Sales",
+			want: &sqlQuery{
+				Items: []sqlSelectItem{{Star: true}},
+				From:  sqlSource{Sheet: "Sales", Alias: "Sales"},
+				Limit: -1,
+			},
+		},
+		{
+			name:  "range, alias and aggregates",
+			query: "SELECT DISTINCT s.Region, SUM(Amount) AS total, COUNT(*) n FROM 'Sales'!A1:D5 s GROUP BY s.Region ORDER BY total DESC, 1 LIMIT 0",
+			want: &sqlQuery{
+				Distinct: true,
+				Items: []sqlSelectItem{
+					{Column: "s.Region"},
+					{Column: "Amount", Aggregate: "sum", Alias: "total"},
+					{Column: "*", Aggregate: "count", Alias: "n"},
+				},
+				From:    sqlSource{Sheet: "Sales", Range: "A1:D5", Alias: "s"},
+				GroupBy: []string{"s.Region"},
+				OrderBy: []sqlOrder{{Column: "total", Desc: true}, {Column: "1"}},
+				Limit:   0,
+			},
+		},
+		{
+			name:  "left join",
+			query: "SELECT [Manager] FROM Sales s LEFT OUTER JOIN Regions AS r ON s.Region = r.Code",
+			want: &sqlQuery{
+				Items: []sqlSelectItem{{Column: "Manager"}},
+				From:  sqlSource{Sheet: "Sales", Alias: "s"},
+				Joins: []sqlJoin{{Source: sqlSource{Sheet: "Regions", Alias: "r"}, Left: true, OnA: "s.Region", OnB: "r.Code"}},
+				Limit: -1,
+			},
+		},
+		{
+			name:  "typed literals",
+			query: "SELECT Id FROM Sales WHERE Paid = TRUE AND Id = '007' OR Amount > -1.5",
+			want: &sqlQuery{
+				Items: []sqlSelectItem{{Column: "Id"}},
+				From:  sqlSource{Sheet: "Sales", Alias: "Sales"},
+				Where: &sqlLogical{
+					Op: "OR",
+					Left: &sqlLogical{
+						Op:    "AND",
+						Left:  &sqlCompare{Op: "=", Left: &sqlColumnRef{Name: "Paid"}, Right: &sqlLiteral{Value: true}},
+						Right: &sqlCompare{Op: "=", Left: &sqlColumnRef{Name: "Id"}, Right: &sqlLiteral{Value: "007"}},
+					},
+					Right: &sqlCompare{Op: ">", Left: &sqlColumnRef{Name: "Amount"}, Right: &sqlLiteral{Value: -1.5}},
+				},
+				Limit: -1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSQL(tt.query)
+			if err != nil {
+				t.Fatalf("parseSQL(%q) error: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSQL(%q) = %#v, want %#v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSQLErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1 FROM Sales", "expected column name"},
+		{"SELECT Id Sales", "expected FROM"},
+		{"SELECT Id FROM Sales LEFT Regions", "expected JOIN"},
+		{"SELECT Id FROM Sales LIMIT -1", "invalid LIMIT"},
+		{"SELECT SUM(*) FROM Sales", "SUM(*) is not supported"},
+		{"SELECT Id FROM Sales WHERE Id LIKE 1", "LIKE requires a string pattern"},
+		{"SELECT Id FROM Sales WHERE Id = 'open", "unterminated"},
+		{"SELECT Id FROM Sales extra tokens", "unexpected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := parseSQL(tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseSQL(%q) error = %v, want it to contain %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunSQL(t *testing.T) {
+	f := newSQLTestFile(t)
+	tests := []struct {
+		name    string
+		query   string
+		columns []string
+		rows    [][]interface{}
+	}{
+		{
+			name:    "text that looks like a number stays text",
+			query:   "SELECT Id FROM Sales WHERE Id = '007'",
+			columns: []string{"Id"},
+			rows:    [][]interface{}{{"007"}},
+		},
+		{
+			name:    "number matches only numbers",
+			query:   "SELECT Id FROM Sales WHERE Id = 7",
+			columns: []string{"Id"},
+			rows:    [][]interface{}{{7.0}},
+		},
+		{
+			name:    "boolean literal",
+			query:   "SELECT Id, Paid FROM Sales WHERE Paid = TRUE",
+			columns: []string{"Id", "Paid"},
+			rows:    [][]interface{}{{"007", true}, {"B2", true}},
+		},
+		{
+			name:    "NOT, IN and BETWEEN",
+			query:   "SELECT Id FROM Sales WHERE NOT Paid = TRUE AND Region IN ('N', 'S') AND Amount BETWEEN 25 AND 50",
+			columns: []string{"Id"},
+			rows:    [][]interface{}{{7.0}, {"A1"}},
+		},
+		{
+			name:    "LIKE",
+			query:   "SELECT Id FROM Sales WHERE Id LIKE 'a%'",
+			columns: []string{"Id"},
+			rows:    [][]interface{}{{"A1"}},
+		},
+		{
+			name:    "group by with aggregates",
+			query:   "SELECT Region, SUM(Amount) AS total, COUNT(*) AS n FROM Sales GROUP BY Region ORDER BY total DESC",
+			columns: []string{"Region", "total", "n"},
+			rows:    [][]interface{}{{"N", 125.0, 2.0}, {"W", 75.0, 1.0}, {"S", 50.0, 1.0}},
+		},
+		{
+			name:    "group by boolean",
+			query:   "SELECT Paid, MIN(Amount) FROM Sales GROUP BY Paid ORDER BY Paid",
+			columns: []string{"Paid", "min(Amount)"},
+			rows:    [][]interface{}{{false, 25.0}, {true, 75.0}},
+		},
+		{
+			name:    "inner join",
+			query:   "SELECT s.Id, r.Manager FROM Sales s JOIN Regions r ON s.Region = r.Code ORDER BY s.Amount",
+			columns: []string{"Id", "Manager"},
+			rows:    [][]interface{}{{"A1", "Ana"}, {7.0, "Ben"}, {"007", "Ana"}},
+		},
+		{
+			name:    "left join keeps unmatched rows",
+			query:   "SELECT s.Id, r.Manager FROM Sales s LEFT JOIN Regions r ON r.Code = s.Region WHERE r.Manager IS NULL",
+			columns: []string{"Id", "Manager"},
+			rows:    [][]interface{}{{"B2", nil}},
+		},
+		{
+			name:    "limit",
+			query:   "SELECT Id FROM Sales ORDER BY Amount DESC LIMIT 2",
+			columns: []string{"Id"},
+			rows:    [][]interface{}{{"007"}, {"B2"}},
+		},
+		{
+			name:    "limit 0",
+			query:   "SELECT Id FROM Sales LIMIT 0",
+			columns: []string{"Id"},
+			rows:    [][]interface{}{},
+		},
+		{
+			name:    "distinct",
+			query:   "SELECT DISTINCT Region FROM 'Sales'!A1:D4",
+			columns: []string{"Region"},
+			rows:    [][]interface{}{{"N"}, {"S"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parseSQL(tt.query)
+			if err != nil {
+				t.Fatalf("parseSQL(%q) error: %v", tt.query, err)
+			}
+			got, err := runSQL(f, q)
+			if err != nil {
+				t.Fatalf("runSQL(%q) error: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got.Columns, tt.columns) {
+				t.Errorf("columns = %v, want %v", got.Columns, tt.columns)
+			}
+			if !reflect.DeepEqual(got.Rows, tt.rows) {
+				t.Errorf("rows = %v, want %v", got.Rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestRunSQLErrors(t *testing.T) {
+	f := newSQLTestFile(t)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT Missing FROM Sales", `unknown column "Missing"`},
+		{"SELECT Id FROM Nowhere", "failed to read 'Nowhere'"},
+		{"SELECT Id, SUM(Amount) FROM Sales GROUP BY Region", "must appear in GROUP BY"},
+		{"SELECT Id FROM Sales ORDER BY 3", "out of range"},
+		{"SELECT Code FROM Sales s JOIN Regions r ON s.Region = s.Id", "must compare a column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseSQL(tt.query)
+			if err != nil {
+				t.Fatalf("parseSQL(%q) error: %v", tt.query, err)
+			}
+			_, err = runSQL(f, q)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runSQL(%q) error = %v, want it to contain %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// headerStyle is the formatting of the header rows written by the streaming writer.
+// Header text is bold unless bold is set to false.
+type headerStyle struct {
+	Bold            *bool   `json:"bold"`
+	Italic          bool    `json:"italic"`
+	FontSize        float64 `json:"font_size"`
+	FontColor       string  `json:"font_color"`
+	BgColor         string  `json:"bg_color"`
+	HorizontalAlign string  `json:"horizontal_align"`
+	WrapText        bool    `json:"wrap_text"`
+	BorderType      string  `json:"border_type"`
+	BorderColor     string  `json:"border_color"`
+}
+
+// toExcelize converts the header style to an excelize style
+func (h headerStyle) toExcelize() (*excelize.Style, error) {
+	style := &excelize.Style{
+		Font: &excelize.Font{
+			Bold:   h.Bold == nil || *h.Bold,
+			Italic: h.Italic,
+			Size:   h.FontSize,
+			Color:  h.FontColor,
+		},
+	}
+	if h.BgColor != "" {
+		style.Fill = excelize.Fill{Type: "pattern", Color: []string{h.BgColor}, Pattern: 1}
+	}
+	if h.HorizontalAlign != "" || h.WrapText {
+		style.Alignment = &excelize.Alignment{Horizontal: h.HorizontalAlign, WrapText: h.WrapText}
+	}
+	if h.BorderType != "" {
+		borderStyle, ok := borderStyles[h.BorderType]
+		if !ok {
+			return nil, fmt.Errorf("invalid border type: %s", h.BorderType)
+		}
+		for _, side := range []string{"top", "right", "bottom", "left"} {
+			style.Border = append(style.Border, excelize.Border{Type: side, Style: borderStyle, Color: h.BorderColor})
+		}
+	}
+	return style, nil
+}
+
+// streamLayout is the layout applied while streaming rows to a sheet
+type streamLayout struct {
+	HeaderRows   int
+	HeaderStyle  *excelize.Style
+	ColumnWidths []float64
+	MergeCells   []string
+}
+
+// isSheetEmpty reports whether a worksheet has no cells at all. A cell without a
+// value still counts, since it may hold a formula that was never calculated or
+// a style, both of which the stream writer would discard.
+func isSheetEmpty(f *excelize.File, sheet string) (bool, error) {
+	content, err := readWorksheetPart(f, sheet)
+	if err != nil {
+		return false, err
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to parse worksheet '%s': %w", sheet, err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "c" {
+			return false, nil
+		}
+	}
+}
+
+// streamRows writes rows to an empty worksheet with a stream writer, starting at
+// col and row. Column widths and merged cells are relative to the sheet, with
+// widths counted from col. The stream writer replaces the sheet's cells, so the
+// sheet must not hold data. It returns the range written.
+func streamRows(f *excelize.File, sheet string, col, row int, rows [][]interface{}, layout streamLayout) (string, error) {
+	for _, ref := range layout.MergeCells {
+		if _, _, _, _, err := parseRangeRef(ref); err != nil {
+			return "", fmt.Errorf("merge_cells: %w", err)
+		}
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return "", err
+	}
+	for i, width := range layout.ColumnWidths {
+		if width <= 0 {
+			continue
+		}
+		if err := sw.SetColWidth(col+i, col+i, width); err != nil {
+			return "", fmt.Errorf("invalid width for column %d: %w", col+i, err)
+		}
+	}
+	headerStyleID := 0
+	if layout.HeaderRows > 0 && layout.HeaderStyle != nil {
+		if headerStyleID, err = f.NewStyle(layout.HeaderStyle); err != nil {
+			return "", fmt.Errorf("failed to create header style: %w", err)
+		}
+	}
+
+	width := 0
+	for i, values := range rows {
+		if i < layout.HeaderRows && headerStyleID != 0 {
+			styled := make([]interface{}, len(values))
+			for j, value := range values {
+				styled[j] = excelize.Cell{StyleID: headerStyleID, Value: value}
+			}
+			values = styled
+		}
+		cell, err := excelize.CoordinatesToCellName(col, row+i)
+		if err != nil {
+			return "", err
+		}
+		if err := sw.SetRow(cell, values); err != nil {
+			return "", fmt.Errorf("failed to write row %d: %w", i+1, err)
+		}
+		width = max(width, len(values))
+	}
+	for _, ref := range layout.MergeCells {
+		col1, row1, col2, row2, _ := parseRangeRef(ref)
+		topLeft, _ := excelize.CoordinatesToCellName(col1, row1)
+		bottomRight, _ := excelize.CoordinatesToCellName(col2, row2)
+		if err := sw.MergeCell(topLeft, bottomRight); err != nil {
+			return "", fmt.Errorf("failed to merge %s: %w", ref, err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return "", err
+	}
+
+	if len(rows) == 0 || width == 0 {
+		return "", nil
+	}
+	startCell, _ := excelize.CoordinatesToCellName(col, row)
+	endCell, _ := excelize.CoordinatesToCellName(col+width-1, row+len(rows)-1)
+	return startCell + ":" + endCell, nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// columnType is the type hint of a written column. Layout is the input layout of
+// dates, and NumberFormat overrides the default number format of the type.
+type columnType struct {
+	Type         string `json:"type"`
+	Layout       string `json:"layout"`
+	NumberFormat string `json:"number_format"`
+}
+
+// columnTypeNames lists the accepted column types
+var columnTypeNames = []string{"auto", "text", "number", "date", "boolean", "formula"}
+
+// defaultDateLayouts are tried, in order, for dates without a layout
+var defaultDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// dateLayoutTokens converts the tokens of a date layout such as "DD/MM/YYYY HH:mm"
+// to Go layout elements, longest token first
+var dateLayoutTokens = strings.NewReplacer(
+	"YYYY", "2006", "yyyy", "2006",
+	"MMMM", "January", "MMM", "Jan",
+	"YY", "06", "yy", "06",
+	"MM", "01", "DD", "02", "dd", "02",
+	"HH", "15", "hh", "03",
+	"mm", "04", "ss", "05",
+	"M", "1", "D", "2", "d", "2",
+	"H", "15", "h", "3",
+	"A", "PM",
+)
+
+// goDateLayout converts a date layout such as "DD/MM/YYYY" to a Go time layout.
+// Layouts already written in Go form, such as "02/01/2006", are kept.
+func goDateLayout(layout string) string {
+	if strings.Contains(layout, "2006") || strings.Contains(layout, "06") && strings.Contains(layout, "01") {
+		return layout
+	}
+	return dateLayoutTokens.Replace(layout)
+}
+
+// parseColumnTypes parses the column_types argument: each item is a type name,
+// an object with a type and options, or null for auto
+func parseColumnTypes(arg []interface{}) ([]columnType, error) {
+	types := make([]columnType, len(arg))
+	for i, item := range arg {
+		switch v := item.(type) {
+		case nil:
+			types[i].Type = "auto"
+		case string:
+			types[i].Type = v
+		case map[string]interface{}:
+			if name, ok := v["type"].(string); ok {
+				types[i].Type = name
+			}
+			types[i].Layout, _ = v["layout"].(string)
+			types[i].NumberFormat, _ = v["number_format"].(string)
+		default:
+			return nil, fmt.Errorf("column_types[%d] must be a type name or an object", i)
+		}
+		if err := types[i].normalize(); err != nil {
+			return nil, fmt.Errorf("column_types[%d]: %w", i, err)
+		}
+	}
+	return types, nil
+}
+
+// normalize lowercases the type name, defaulting to auto, and checks that it is known
+func (c *columnType) normalize() error {
+	c.Type = strings.ToLower(c.Type)
+	if c.Type == "" {
+		c.Type = "auto"
+	}
+	for _, name := range columnTypeNames {
+		if c.Type == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown type %q, expected one of %s", c.Type, strings.Join(columnTypeNames, ", "))
+}
+
+// largeNumber is the magnitude from which Excel's General format shows whole numbers in scientific notation
+const largeNumber = 1e11
+
+// numberFormat returns the number format of a cell of the column holding a
+// converted value, or "" to leave the cell's format unchanged
+func (c columnType) numberFormat(value interface{}) string {
+	if c.NumberFormat != "" {
+		return c.NumberFormat
+	}
+	switch c.Type {
+	case "text":
+		return "@"
+	case "date":
+		if t, ok := value.(time.Time); ok && (t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0) {
+			return "yyyy-mm-dd hh:mm:ss"
+		}
+		return "yyyy-mm-dd"
+	case "number":
+		// Keep large whole numbers such as IDs readable
+		if v, ok := value.(float64); ok && math.Abs(v) >= largeNumber && v == math.Trunc(v) {
+			return "0"
+		}
+	}
+	return ""
+}
+
+// convert converts a JSON value to the value to store for the column type.
+// A nil result leaves the cell empty.
+func (c columnType) convert(value interface{}) (interface{}, error) {
+	if value == nil || value == "" {
+		return nil, nil
+	}
+	switch c.Type {
+	case "text":
+		if v, ok := value.(float64); ok {
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+		return fmt.Sprint(value), nil
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			num, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			return num, nil
+		}
+		return nil, fmt.Errorf("%v is not a number", value)
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, nil
+			}
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "yes", "y", "1":
+				return true, nil
+			case "false", "no", "n", "0":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("%v is not a boolean", value)
+	case "date":
+		return c.parseDate(value)
+	case "formula":
+		if _, ok := value.(string); !ok {
+			return nil, errors.New("a formula must be a string")
+		}
+	}
+	return value, nil
+}
+
+// parseDate parses a date in the column's layout, or in an ISO 8601 layout if it
+// has none. Numbers are taken as Excel serial dates.
+func (c columnType) parseDate(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		if v < 0 || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%v is not a date", v)
+		}
+		t, err := excelize.ExcelDateToTime(v, false)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	case string:
+		v = strings.TrimSpace(v)
+		layouts := defaultDateLayouts
+		if c.Layout != "" {
+			layouts = []string{goDateLayout(c.Layout)}
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		if c.Layout != "" {
+			return nil, fmt.Errorf("%q does not match the date layout %q", v, c.Layout)
+		}
+		return nil, fmt.Errorf("%q is not an ISO 8601 date; set a layout such as 'DD/MM/YYYY'", v)
+	}
+	return nil, fmt.Errorf("%v is not a date", value)
+}
+
+// writeTypedRows writes rows starting at col and row, converting the values of each
+// column to its type and giving the cells of typed columns their number format.
+// Columns without a type are written as they are. The first headerRows rows are
+// written as they are and keep their format. Every value is converted before
+// the sheet is changed.
+func writeTypedRows(f *excelize.File, sheet string, col, row int, data [][]interface{}, types []columnType, headerRows int) error {
+	converted := make([][]interface{}, len(data))
+	for i, rowData := range data {
+		converted[i] = rowData
+		if i < headerRows {
+			continue
+		}
+		converted[i] = make([]interface{}, len(rowData))
+		for j, value := range rowData {
+			if j >= len(types) {
+				converted[i][j] = value
+				continue
+			}
+			v, err := types[j].convert(value)
+			if err != nil {
+				cell, _ := excelize.CoordinatesToCellName(col+j, row+i)
+				return fmt.Errorf("%s: %w", cell, err)
+			}
+			converted[i][j] = v
+		}
+	}
+
+	styles := make(map[string]map[int]int)
+	for i, rowData := range converted {
+		for j, value := range rowData {
+			cell, err := excelize.CoordinatesToCellName(col+j, row+i)
+			if err != nil {
+				return err
+			}
+			if i >= headerRows && j < len(types) && types[j].Type == "formula" {
+				if err := setFormula(f, sheet, cell, value); err != nil {
+					return fmt.Errorf("%s: %w", cell, err)
+				}
+			} else if err := f.SetCellValue(sheet, cell, value); err != nil {
+				return fmt.Errorf("%s: %w", cell, err)
+			}
+			if i < headerRows || j >= len(types) {
+				continue
+			}
+			if format := types[j].numberFormat(value); format != "" {
+				if err := applyNumberFormat(f, sheet, cell, format, styles); err != nil {
+					return fmt.Errorf("%s: %w", cell, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setFormula sets the formula of a cell; a leading '=' is optional. An empty value clears the cell.
+func setFormula(f *excelize.File, sheet, cell string, value interface{}) error {
+	if value == nil || value == "" {
+		return f.SetCellValue(sheet, cell, nil)
+	}
+	formula, ok := value.(string)
+	if !ok {
+		return errors.New("a formula must be a string")
+	}
+	return f.SetCellFormula(sheet, cell, strings.TrimPrefix(formula, "="))
+}
+
+// applyNumberFormat gives a cell a number format while keeping the rest of its
+// style. styles caches the derived style of each format and original style.
+func applyNumberFormat(f *excelize.File, sheet, cell, format string, styles map[string]map[int]int) error {
+	styleID, err := f.GetCellStyle(sheet, cell)
+	if err != nil {
+		return err
+	}
+	if styles[format] == nil {
+		styles[format] = make(map[int]int)
+	}
+	derived, ok := styles[format][styleID]
+	if !ok {
+		style, err := f.GetStyle(styleID)
+		if err != nil {
+			return err
+		}
+		style.NumFmt = 0
+		style.CustomNumFmt = &format
+		if derived, err = f.NewStyle(style); err != nil {
+			return err
+		}
+		styles[format][styleID] = derived
+	}
+	return f.SetCellStyle(sheet, cell, cell, derived)
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// upsertCounts counts the rows an upsert inserted, updated, left unchanged and deleted
+type upsertCounts struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+	Deleted   int
+}
+
+// upsertTable is the header-based table an upsert works on, with the raw
+// values of its rows as stored in the sheet
+type upsertTable struct {
+	dataTable
+	Col, HeaderRow int
+	LastRow        int
+	Raw            map[int][]string
+	Bounded        bool
+}
+
+// readUpsertTable reads the table at cellRange, or the whole sheet starting at A1
+// if cellRange is empty, keeping the sheet row of every data row
+func readUpsertTable(f *excelize.File, sheet, cellRange string) (*upsertTable, error) {
+	col1, row1, col2, row2 := 1, 1, 0, 0
+	if cellRange != "" {
+		var err error
+		if col1, row1, col2, row2, err = parseRangeRef(cellRange); err != nil {
+			return nil, err
+		}
+	}
+	t := &upsertTable{Col: col1, HeaderRow: row1, LastRow: row1, Raw: make(map[int][]string), Bounded: row2 > 0}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var header []string
+	for rowNum := 1; rows.Next(); rowNum++ {
+		if rowNum < row1 {
+			continue
+		}
+		if row2 > 0 && rowNum > row2 {
+			break
+		}
+		cols, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, err
+		}
+		if rowNum == row1 {
+			header = cols
+		} else {
+			t.Raw[rowNum] = cols
+		}
+		if cellRange == "" {
+			col2 = max(col2, len(cols))
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("range contains no header row")
+	}
+	if col2 < col1 {
+		return nil, errors.New("range contains no columns")
+	}
+
+	t.Columns = make([]string, col2-col1+1)
+	for i := range t.Columns {
+		t.Columns[i] = cellAt(header, col1+i)
+		if t.Columns[i] == "" {
+			t.Columns[i], _ = excelize.ColumnNumberToName(col1 + i)
+		}
+	}
+	for rowNum, cols := range t.Raw {
+		for col := col1; col <= col2; col++ {
+			if cellAt(cols, col) != "" {
+				t.LastRow = max(t.LastRow, rowNum)
+				break
+			}
+		}
+	}
+	return t, nil
+}
+
+// upsertKey returns the key a cell or record value matches on. Numbers are keyed
+// by their value and text by its exact string, so "007", "7" and "1e3" are
+// distinct keys, while text matches a number only when it is the number's
+// plain decimal form.
+func upsertKey(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return fmt.Sprint(value)
+}
+
+// sameCellValue reports whether a cell value, as returned by cellValue, already
+// holds a record value. Values of different types never match, so that a
+// number stored as text is rewritten as a number.
+func sameCellValue(current, value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		c, ok := current.(float64)
+		return ok && c == v
+	case bool:
+		c, ok := current.(bool)
+		return ok && c == v
+	case string:
+		if v != "" {
+			c, ok := current.(string)
+			return ok && c == v
+		}
+	}
+	return current == nil
+}
+
+// upsertRecords updates the rows of a table whose key matches a record, appends
+// records with new keys below the last row and, if deleteMissing is set, deletes
+// rows whose key is in no record. Records only change the columns they name.
+// If the rows form a table (list object), the table is extended over the new rows.
+func upsertRecords(f *excelize.File, sheet string, t *upsertTable, table *sheetTable, keyColumn string,
+	records []map[string]interface{}, deleteMissing bool) (*upsertCounts, error) {
+	keyIndex, err := t.columnIndex(keyColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate every record before changing the sheet
+	columns := make([]map[string]int, len(records))
+	keys := make([]string, len(records))
+	recordKeys := make(map[string]bool)
+	for i, record := range records {
+		columns[i] = make(map[string]int, len(record))
+		for name, value := range record {
+			index, err := t.columnIndex(name)
+			if err != nil {
+				return nil, fmt.Errorf("record %d: %w", i+1, err)
+			}
+			switch value.(type) {
+			case nil, string, float64, bool:
+			default:
+				return nil, fmt.Errorf("record %d: value of %q must be a string, number, boolean or null", i+1, name)
+			}
+			columns[i][name] = index
+		}
+		var key interface{}
+		for name, index := range columns[i] {
+			if index == keyIndex && record[name] != "" {
+				key = record[name]
+			}
+		}
+		if key == nil {
+			return nil, fmt.Errorf("record %d has no value for key column %q", i+1, t.Columns[keyIndex])
+		}
+		keys[i] = upsertKey(key)
+		if recordKeys[keys[i]] {
+			return nil, fmt.Errorf("record %d repeats key %s", i+1, keys[i])
+		}
+		recordKeys[keys[i]] = true
+	}
+
+	rowsByKey := make(map[string][]int)
+	for rowNum, cols := range t.Raw {
+		key, err := cellValue(f, sheet, t.Col+keyIndex, rowNum, cellAt(cols, t.Col+keyIndex))
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			rowsByKey[upsertKey(key)] = append(rowsByKey[upsertKey(key)], rowNum)
+		}
+	}
+
+	inserts := 0
+	for _, key := range keys {
+		if len(rowsByKey[key]) == 0 {
+			inserts++
+		}
+	}
+	if inserts > 0 && t.Bounded {
+		// The rows below a bounded range must be free to grow into
+		last, err := lastUsedRow(f, sheet, t.Col, t.Col+len(t.Columns)-1, t.LastRow+inserts)
+		if err != nil {
+			return nil, err
+		}
+		if last > t.LastRow {
+			return nil, errors.New("the cells below the table are not empty")
+		}
+	}
+
+	counts := &upsertCounts{}
+	nextRow := t.LastRow + 1
+	for i, record := range records {
+		rows := rowsByKey[keys[i]]
+		if len(rows) == 0 {
+			for name, index := range columns[i] {
+				cell, _ := excelize.CoordinatesToCellName(t.Col+index, nextRow)
+				if err := f.SetCellValue(sheet, cell, record[name]); err != nil {
+					return nil, err
+				}
+			}
+			nextRow++
+			counts.Inserted++
+			continue
+		}
+		for _, rowNum := range rows {
+			changed := false
+			for name, index := range columns[i] {
+				current, err := cellValue(f, sheet, t.Col+index, rowNum, cellAt(t.Raw[rowNum], t.Col+index))
+				if err != nil {
+					return nil, err
+				}
+				if sameCellValue(current, record[name]) {
+					continue
+				}
+				cell, _ := excelize.CoordinatesToCellName(t.Col+index, rowNum)
+				if err := f.SetCellValue(sheet, cell, record[name]); err != nil {
+					return nil, err
+				}
+				changed = true
+			}
+			if changed {
+				counts.Updated++
+			} else {
+				counts.Unchanged++
+			}
+		}
+	}
+	if table != nil && nextRow-1 > t.LastRow {
+		_, _, _, tableEnd, _ := parseRangeRef(table.Ref)
+		if nextRow-1 > tableEnd {
+			start, _ := excelize.CoordinatesToCellName(t.Col, t.HeaderRow)
+			end, _ := excelize.CoordinatesToCellName(t.Col+len(t.Columns)-1, nextRow-1)
+			if err := resizeTable(f, table, start+":"+end); err != nil {
+				return nil, fmt.Errorf("failed to extend table '%s': %w", table.Name, err)
+			}
+		}
+	}
+
+	// Delete from the bottom up so that the rows still to delete keep their numbers
+	if deleteMissing {
+		var missing []int
+		for key, rows := range rowsByKey {
+			if !recordKeys[key] {
+				missing = append(missing, rows...)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(missing)))
+		for _, rowNum := range missing {
+			if err := f.RemoveRow(sheet, rowNum); err != nil {
+				return nil, err
+			}
+		}
+		counts.Deleted = len(missing)
+	}
+	return counts, nil
+}
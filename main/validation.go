@@ -0,0 +1,469 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// validationTypes maps tool type names to data validation types
+var validationTypes = map[string]excelize.DataValidationType{
+	"list":        excelize.DataValidationTypeList,
+	"whole":       excelize.DataValidationTypeWhole,
+	"decimal":     excelize.DataValidationTypeDecimal,
+	"date":        excelize.DataValidationTypeDate,
+	"time":        excelize.DataValidationTypeTime,
+	"text_length": excelize.DataValidationTypeTextLength,
+	"custom":      excelize.DataValidationTypeCustom,
+}
+
+// validationOperators maps tool operator names to data validation operators
+var validationOperators = map[string]excelize.DataValidationOperator{
+	"between":               excelize.DataValidationOperatorBetween,
+	"not_between":           excelize.DataValidationOperatorNotBetween,
+	"equal":                 excelize.DataValidationOperatorEqual,
+	"not_equal":             excelize.DataValidationOperatorNotEqual,
+	"greater_than":          excelize.DataValidationOperatorGreaterThan,
+	"greater_than_or_equal": excelize.DataValidationOperatorGreaterThanOrEqual,
+	"less_than":             excelize.DataValidationOperatorLessThan,
+	"less_than_or_equal":    excelize.DataValidationOperatorLessThanOrEqual,
+}
+
+// validationErrorStyles maps tool error style names to data validation error styles
+var validationErrorStyles = map[string]excelize.DataValidationErrorStyle{
+	"stop":        excelize.DataValidationErrorStyleStop,
+	"warning":     excelize.DataValidationErrorStyleWarning,
+	"information": excelize.DataValidationErrorStyleInformation,
+}
+
+// formulaXMLEscaper escapes formulas stored as inner XML of a data validation rule
+var formulaXMLEscaper = strings.NewReplacer(`&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`)
+
+// validationBound converts a minimum/maximum argument to a data validation formula.
+// ISO dates (2006-01-02) become DATE() and clock times (15:04) become TIME() for
+// date and time rules; numbers and other strings are passed through.
+func validationBound(value interface{}, validationType string) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		v = strings.TrimPrefix(v, "=")
+		if validationType == "date" {
+			if t, err := time.Parse("2006-01-02", v); err == nil {
+				return fmt.Sprintf("DATE(%d,%d,%d)", t.Year(), t.Month(), t.Day()), nil
+			}
+		}
+		if validationType == "time" {
+			for _, layout := range []string{"15:04:05", "15:04"} {
+				if t, err := time.Parse(layout, v); err == nil {
+					return fmt.Sprintf("TIME(%d,%d,%d)", t.Hour(), t.Minute(), t.Second()), nil
+				}
+			}
+		}
+		return formulaXMLEscaper.Replace(v), nil
+	case nil:
+		return "", nil
+	}
+	return nil, fmt.Errorf("unsupported bound value %v", value)
+}
+
+// validationTypeName converts a stored data validation type back to its tool name
+func validationTypeName(storedType string) string {
+	if storedType == "textLength" {
+		return "text_length"
+	}
+	return storedType
+}
+
+// validationOperatorName converts a stored operator such as "greaterThan" back to its tool name
+func validationOperatorName(storedOperator string) string {
+	var sb strings.Builder
+	for _, ch := range storedOperator {
+		if ch >= 'A' && ch <= 'Z' {
+			sb.WriteRune('_')
+			ch += 'a' - 'A'
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
+
+// derefString returns the string a pointer refers to, or "" for nil
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// cellRect is an inclusive rectangle of 1-based cell coordinates
+type cellRect struct {
+	col1, row1, col2, row2 int
+}
+
+func (r cellRect) contains(col, row int) bool {
+	return col >= r.col1 && col <= r.col2 && row >= r.row1 && row <= r.row2
+}
+
+// parseRefEnd parses one end of a range, which may be a cell (A1), a column (A) or a row (1)
+func parseRefEnd(ref string, isEnd bool) (col, row int, err error) {
+	if col, row, err = excelize.CellNameToCoordinates(ref); err == nil {
+		return col, row, nil
+	}
+	if col, err = excelize.ColumnNameToNumber(ref); err == nil {
+		if isEnd {
+			return col, excelize.TotalRows, nil
+		}
+		return col, 1, nil
+	}
+	if row, err = strconv.Atoi(ref); err == nil && row > 0 {
+		if isEnd {
+			return excelize.MaxColumns, row, nil
+		}
+		return 1, row, nil
+	}
+	return 0, 0, fmt.Errorf("invalid cell reference %q", ref)
+}
+
+// parseSqref parses a space-separated list of references such as "A1 B2:C10 D:D"
+func parseSqref(sqref string) ([]cellRect, error) {
+	var rects []cellRect
+	for _, ref := range strings.Fields(strings.ReplaceAll(sqref, "$", "")) {
+		start, end := ref, ref
+		if idx := strings.Index(ref, ":"); idx != -1 {
+			start, end = ref[:idx], ref[idx+1:]
+		}
+		col1, row1, err := parseRefEnd(start, false)
+		if err != nil {
+			return nil, err
+		}
+		col2, row2, err := parseRefEnd(end, true)
+		if err != nil {
+			return nil, err
+		}
+		if col1 > col2 {
+			col1, col2 = col2, col1
+		}
+		if row1 > row2 {
+			row1, row2 = row2, row1
+		}
+		rects = append(rects, cellRect{col1, row1, col2, row2})
+	}
+	return rects, nil
+}
+
+// resolveSqref resolves the range argument of a data validation tool, which may be
+// a defined name or a space-separated list of references on sheet, to its sheet and
+// references. A defined name takes precedence over a column reference of the same
+// spelling, such as "Tax".
+func resolveSqref(f *excelize.File, sheet, sqref string) (string, string, error) {
+	if refSheet, refRange, ok := resolveDefinedName(f, sheet, strings.TrimSpace(sqref)); ok && refSheet != "" {
+		return refSheet, refRange, nil
+	}
+	if _, err := parseSqref(sqref); err != nil {
+		return "", "", fmt.Errorf("%q is neither a cell range nor a defined name", sqref)
+	}
+	if sheet == "" {
+		return "", "", errors.New("sheet_name is required unless range is a defined name")
+	}
+	return sheet, sqref, nil
+}
+
+var (
+	dateFormulaPattern = regexp.MustCompile(`(?i)^DATE\((\d+),(\d+),(\d+)\)$`)
+	timeFormulaPattern = regexp.MustCompile(`(?i)^TIME\((\d+),(\d+),(\d+)\)$`)
+)
+
+// excelEpoch is the zero point of Excel serial dates in the 1900 date system
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// validationCheck evaluates cells against one data validation rule
+type validationCheck struct {
+	rule     *excelize.DataValidation
+	rects    []cellRect
+	items    map[string]bool
+	min, max float64
+}
+
+// validationViolation is a cell whose value breaks its data validation rule
+type validationViolation struct {
+	Sheet  string `json:"sheet"`
+	Cell   string `json:"cell"`
+	Value  string `json:"value"`
+	Range  string `json:"rule_range"`
+	Type   string `json:"rule_type"`
+	Reason string `json:"reason"`
+}
+
+// skippedValidation is a rule that could not be evaluated
+type skippedValidation struct {
+	Sheet  string `json:"sheet"`
+	Range  string `json:"rule_range"`
+	Type   string `json:"rule_type"`
+	Reason string `json:"reason"`
+}
+
+// evalValidationBound evaluates a rule bound: a number, DATE(), TIME() or a cell reference
+func evalValidationBound(f *excelize.File, sheet, formula string) (float64, error) {
+	formula = strings.TrimSpace(strings.TrimPrefix(formula, "="))
+	if num, err := strconv.ParseFloat(formula, 64); err == nil {
+		return num, nil
+	}
+	if m := dateFormulaPattern.FindStringSubmatch(strings.ReplaceAll(formula, " ", "")); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Sub(excelEpoch).Hours() / 24, nil
+	}
+	if m := timeFormulaPattern.FindStringSubmatch(strings.ReplaceAll(formula, " ", "")); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		second, _ := strconv.Atoi(m[3])
+		return float64(hour*3600+minute*60+second) / 86400, nil
+	}
+	refSheet, cell := splitSheetRef(formula)
+	if _, _, err := excelize.CellNameToCoordinates(cell); err == nil {
+		if refSheet == "" {
+			refSheet = sheet
+		}
+		value, err := f.GetCellValue(refSheet, cell, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return 0, err
+		}
+		num, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bound cell %s!%s does not hold a number", refSheet, cell)
+		}
+		return num, nil
+	}
+	return 0, fmt.Errorf("bound formula %q cannot be evaluated", formula)
+}
+
+// listSourceValues reads the allowed values of a range-sourced dropdown list
+func listSourceValues(f *excelize.File, sheet, source string) ([]string, error) {
+	source = strings.TrimPrefix(strings.TrimSpace(source), "=")
+	for _, dn := range f.GetDefinedName() {
+		if strings.EqualFold(dn.Name, source) && (dn.Scope == "Workbook" || dn.Scope == sheet) {
+			source = dn.RefersTo
+			break
+		}
+	}
+	refSheet, cellRange := splitSheetRef(source)
+	if refSheet == "" {
+		refSheet = sheet
+	}
+	if !strings.Contains(cellRange, ":") {
+		cellRange += ":" + cellRange
+	}
+	col1, row1, col2, row2, err := parseRangeRef(cellRange)
+	if err != nil {
+		return nil, fmt.Errorf("list source %q cannot be evaluated", source)
+	}
+	var values []string
+	for row := row1; row <= row2; row++ {
+		for col := col1; col <= col2; col++ {
+			cell, _ := excelize.CoordinatesToCellName(col, row)
+			value, err := f.GetCellValue(refSheet, cell, excelize.Options{RawCellValue: true})
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// newValidationCheck prepares a rule for evaluation. An error explains why the rule cannot be checked.
+func newValidationCheck(f *excelize.File, sheet string, dv *excelize.DataValidation) (*validationCheck, error) {
+	rects, err := parseSqref(dv.Sqref)
+	if err != nil {
+		return nil, err
+	}
+	check := &validationCheck{rule: dv, rects: rects}
+	switch dv.Type {
+	case "list":
+		var items []string
+		if strings.HasPrefix(dv.Formula1, "\"") {
+			items = strings.Split(strings.Trim(dv.Formula1, "\""), ",")
+		} else if items, err = listSourceValues(f, sheet, dv.Formula1); err != nil {
+			return nil, err
+		}
+		check.items = make(map[string]bool, len(items))
+		for _, item := range items {
+			check.items[strings.ToLower(strings.TrimSpace(item))] = true
+		}
+	case "whole", "decimal", "date", "time", "textLength":
+		if check.min, err = evalValidationBound(f, sheet, dv.Formula1); err != nil {
+			return nil, err
+		}
+		if dv.Operator == "" || dv.Operator == "between" || dv.Operator == "notBetween" {
+			if check.max, err = evalValidationBound(f, sheet, dv.Formula2); err != nil {
+				return nil, err
+			}
+		}
+	case "custom":
+		return nil, fmt.Errorf("custom formula rules are not evaluated")
+	case "", "none":
+		return nil, fmt.Errorf("rule allows any value")
+	default:
+		return nil, fmt.Errorf("unsupported validation type %q", dv.Type)
+	}
+	return check, nil
+}
+
+// violation returns why a raw cell value breaks the rule, or "" if it is valid
+func (c *validationCheck) violation(value string) string {
+	if c.rule.Type == "list" {
+		if !c.items[strings.ToLower(strings.TrimSpace(value))] {
+			return "value is not in the dropdown list"
+		}
+		return ""
+	}
+
+	var num float64
+	if c.rule.Type == "textLength" {
+		num = float64(utf8.RuneCountInString(value))
+	} else {
+		var err error
+		if num, err = strconv.ParseFloat(value, 64); err != nil {
+			switch c.rule.Type {
+			case "date":
+				return "value is not a date"
+			case "time":
+				return "value is not a time"
+			}
+			return "value is not a number"
+		}
+		if c.rule.Type == "whole" && num != math.Trunc(num) {
+			return "value is not a whole number"
+		}
+	}
+
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	subject := "value"
+	if c.rule.Type == "textLength" {
+		subject = "text length " + format(num)
+	}
+	switch c.rule.Operator {
+	case "", "between":
+		if num < c.min || num > c.max {
+			return fmt.Sprintf("%s is not between %s and %s", subject, format(c.min), format(c.max))
+		}
+	case "notBetween":
+		if num >= c.min && num <= c.max {
+			return fmt.Sprintf("%s is between %s and %s", subject, format(c.min), format(c.max))
+		}
+	case "equal":
+		if num != c.min {
+			return fmt.Sprintf("%s is not equal to %s", subject, format(c.min))
+		}
+	case "notEqual":
+		if num == c.min {
+			return fmt.Sprintf("%s is equal to %s", subject, format(c.min))
+		}
+	case "greaterThan":
+		if num <= c.min {
+			return fmt.Sprintf("%s is not greater than %s", subject, format(c.min))
+		}
+	case "greaterThanOrEqual":
+		if num < c.min {
+			return fmt.Sprintf("%s is less than %s", subject, format(c.min))
+		}
+	case "lessThan":
+		if num >= c.min {
+			return fmt.Sprintf("%s is not less than %s", subject, format(c.min))
+		}
+	case "lessThanOrEqual":
+		if num > c.min {
+			return fmt.Sprintf("%s is greater than %s", subject, format(c.min))
+		}
+	}
+	return ""
+}
+
+// findValidationViolations scans the non-empty cells of a sheet in a single streaming
+// pass and reports those that break a data validation rule covering them. At most
+// limit violations are collected; total counts all of them.
+func findValidationViolations(f *excelize.File, sheet string, limit int) (checked, total int, violations []validationViolation, skipped []skippedValidation, err error) {
+	dataValidations, err := f.GetDataValidations(sheet)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+	var checks []*validationCheck
+	for _, dv := range dataValidations {
+		check, err := newValidationCheck(f, sheet, dv)
+		if err != nil {
+			skipped = append(skipped, skippedValidation{
+				Sheet:  sheet,
+				Range:  dv.Sqref,
+				Type:   validationTypeName(dv.Type),
+				Reason: err.Error(),
+			})
+			continue
+		}
+		checks = append(checks, check)
+	}
+	if len(checks) == 0 {
+		return 0, 0, nil, skipped, nil
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return 0, 0, nil, nil, err
+	}
+	defer rows.Close()
+	for rowNum := 1; rows.Next(); rowNum++ {
+		cols, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+		for i, value := range cols {
+			if value == "" {
+				continue
+			}
+			covered := false
+			for _, check := range checks {
+				inRule := false
+				for _, rect := range check.rects {
+					if rect.contains(i+1, rowNum) {
+						inRule = true
+						break
+					}
+				}
+				if !inRule {
+					continue
+				}
+				covered = true
+				reason := check.violation(value)
+				if reason == "" {
+					continue
+				}
+				total++
+				if len(violations) < limit {
+					cell, _ := excelize.CoordinatesToCellName(i+1, rowNum)
+					violations = append(violations, validationViolation{
+						Sheet:  sheet,
+						Cell:   cell,
+						Value:  value,
+						Range:  check.rule.Sqref,
+						Type:   validationTypeName(check.rule.Type),
+						Reason: reason,
+					})
+				}
+			}
+			if covered {
+				checked++
+			}
+		}
+	}
+	return checked, total, violations, skipped, rows.Error()
+}